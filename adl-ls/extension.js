@@ -0,0 +1,24 @@
+const vscode = require("vscode");
+const { LanguageClient } = require("vscode-languageclient/node");
+
+let client;
+
+function activate(context) {
+  const command = vscode.workspace.getConfiguration("adl").get("server.path", "adl");
+
+  client = new LanguageClient(
+    "adl",
+    "ADL Language Server",
+    { command, args: ["lsp"] },
+    { documentSelector: [{ scheme: "file", language: "adl" }] }
+  );
+
+  context.subscriptions.push(client);
+  return client.start();
+}
+
+function deactivate() {
+  return client ? client.stop() : undefined;
+}
+
+module.exports = { activate, deactivate };
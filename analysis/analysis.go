@@ -0,0 +1,321 @@
+// Package analysis resolves the names in an ADL program to their
+// definitions, for tools such as the language server and the linter.
+package analysis
+
+import (
+	"errors"
+	"reflect"
+
+	"github.com/mislavperi/adl-lang/ast"
+	"github.com/mislavperi/adl-lang/compiler"
+	"github.com/mislavperi/adl-lang/lexer"
+	"github.com/mislavperi/adl-lang/parser"
+	"github.com/mislavperi/adl-lang/representation"
+	symboltable "github.com/mislavperi/adl-lang/symbol_table"
+	"github.com/mislavperi/adl-lang/token"
+)
+
+// Definition is a name bound by a let statement, a function parameter or a
+// builtin.
+type Definition struct {
+	Name  string
+	Scope symboltable.SymbolScope
+
+	// Token is the identifier that introduces the name. Builtins have none.
+	Token token.Token
+
+	// Value is the expression bound by a let statement, and Function the
+	// function a parameter belongs to.
+	Value    ast.Expression
+	Function *ast.FnLiteral
+
+	// Shadows is the definition from an enclosing scope, or the builtin,
+	// that this one hides.
+	Shadows *Definition
+
+	scope *scope
+}
+
+// Reference is an identifier in the source and the definition it resolves
+// to. The identifiers that introduce definitions are references too.
+type Reference struct {
+	Token      token.Token
+	Definition *Definition
+}
+
+// Problem is a parse or compile error at a token.
+type Problem struct {
+	Message string
+	Token   token.Token
+}
+
+// Analysis is what is known about one program.
+type Analysis struct {
+	Program     *ast.Program
+	Comments    []token.Token
+	Problems    []Problem
+	Definitions []*Definition
+	References  []Reference
+
+	scopes   []*scope
+	builtins []*Definition
+	resolved map[token.Token]*Definition
+}
+
+// scope is the part of the source where a function's names are visible,
+// mirroring the symbol tables the compiler enters for each function.
+type scope struct {
+	table       *symboltable.SymbolTable
+	definitions map[string]*Definition
+	outer       *scope
+
+	// start and end bound the scope in the source. The global scope has a
+	// zero end and covers everything.
+	start token.Token
+	end   token.Token
+}
+
+// Analyze parses source, resolves every identifier and collects the errors
+// the parser and compiler report.
+func Analyze(source string) *Analysis {
+	l := lexer.New(source)
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	a := &Analysis{Program: program, Comments: l.Comments(), resolved: map[token.Token]*Definition{}}
+	for _, err := range p.DetailedErrors() {
+		a.Problems = append(a.Problems, Problem{Message: err.Message, Token: err.Token})
+	}
+
+	if len(a.Problems) == 0 {
+		if err := compiler.New().Compile(program); err != nil {
+			problem := Problem{Message: err.Error()}
+			var compileErr *compiler.Error
+			if errors.As(err, &compileErr) {
+				problem.Token = compileErr.Token
+			}
+			a.Problems = append(a.Problems, problem)
+		}
+	}
+
+	global := &scope{table: symboltable.NewSymbolTable(), definitions: map[string]*Definition{}}
+	for index, builtin := range representation.Builtins {
+		symbol := global.table.DefineBuiltin(index, builtin.Name)
+		definition := &Definition{Name: builtin.Name, Scope: symbol.Scope, scope: global}
+		global.definitions[builtin.Name] = definition
+		a.builtins = append(a.builtins, definition)
+	}
+	a.scopes = append(a.scopes, global)
+
+	closers := matchingBraces(source)
+	for _, statement := range program.Statements {
+		a.walk(statement, global, closers)
+	}
+
+	return a
+}
+
+func (a *Analysis) walk(node ast.Node, s *scope, closers map[token.Token]token.Token) {
+	// Programs with syntax errors contain typed nil nodes.
+	if node == nil || reflect.ValueOf(node).IsNil() {
+		return
+	}
+
+	switch node := node.(type) {
+	case *ast.LetStatement:
+		if node.Name == nil {
+			return
+		}
+		// The compiler defines the name before compiling the value, so a
+		// function can refer to itself.
+		a.define(s, node.Name, &Definition{Value: node.Value})
+		a.walk(node.Value, s, closers)
+	case *ast.ReturnStatement:
+		a.walk(node.ReturnValue, s, closers)
+	case *ast.ExpressionStatement:
+		a.walk(node.Expression, s, closers)
+	case *ast.BlockStatement:
+		for _, statement := range node.Statements {
+			a.walk(statement, s, closers)
+		}
+	case *ast.Identifier:
+		if definition := s.resolve(node.Value); definition != nil {
+			a.References = append(a.References, Reference{Token: node.Token, Definition: definition})
+			a.resolved[node.Token] = definition
+		}
+	case *ast.PrefixExpression:
+		a.walk(node.Right, s, closers)
+	case *ast.InfixExpression:
+		a.walk(node.Left, s, closers)
+		a.walk(node.Right, s, closers)
+	case *ast.IfExpression:
+		a.walk(node.Condition, s, closers)
+		a.walk(node.Consequence, s, closers)
+		a.walk(node.Alternative, s, closers)
+	case *ast.FnLiteral:
+		inner := &scope{
+			table:       symboltable.NewEnclosedSymbolTable(s.table),
+			definitions: map[string]*Definition{},
+			outer:       s,
+			start:       node.Token,
+			end:         closers[node.Token],
+		}
+		a.scopes = append(a.scopes, inner)
+
+		for _, param := range node.Parameters {
+			a.define(inner, param, &Definition{Function: node})
+		}
+		a.walk(node.Body, inner, closers)
+	case *ast.CallExpression:
+		a.walk(node.Function, s, closers)
+		for _, arg := range node.Arguments {
+			a.walk(arg, s, closers)
+		}
+	case *ast.ArrayLiteral:
+		for _, element := range node.Elements {
+			a.walk(element, s, closers)
+		}
+	case *ast.IndexExpression:
+		a.walk(node.Left, s, closers)
+		a.walk(node.Index, s, closers)
+	case *ast.SliceExpression:
+		a.walk(node.Left, s, closers)
+		a.walk(node.Start, s, closers)
+		a.walk(node.End, s, closers)
+	case *ast.HashLiteral:
+		for key, value := range node.Pairs {
+			a.walk(key, s, closers)
+			a.walk(value, s, closers)
+		}
+	}
+}
+
+func (a *Analysis) define(s *scope, name *ast.Identifier, definition *Definition) {
+	symbol := s.table.Define(name.Value)
+
+	if s.outer != nil {
+		definition.Shadows = s.outer.resolve(name.Value)
+	} else if previous := s.definitions[name.Value]; previous != nil && previous.Scope == symboltable.BuiltinScope {
+		definition.Shadows = previous
+	}
+
+	definition.Name = name.Value
+	definition.Scope = symbol.Scope
+	definition.Token = name.Token
+	definition.scope = s
+
+	s.definitions[name.Value] = definition
+	a.Definitions = append(a.Definitions, definition)
+	a.References = append(a.References, Reference{Token: name.Token, Definition: definition})
+	a.resolved[name.Token] = definition
+}
+
+// Resolve returns the definition an identifier refers to, or nil if it is
+// undefined.
+func (a *Analysis) Resolve(identifier *ast.Identifier) *Definition {
+	return a.resolved[identifier.Token]
+}
+
+func (s *scope) resolve(name string) *Definition {
+	for current := s; current != nil; current = current.outer {
+		if definition, ok := current.definitions[name]; ok {
+			return definition
+		}
+	}
+	return nil
+}
+
+// ReferenceAt returns the reference whose identifier covers the 1-based line
+// and byte column.
+func (a *Analysis) ReferenceAt(line int, column int) (Reference, bool) {
+	for _, ref := range a.References {
+		if ref.Token.Line == line && column >= ref.Token.Column && column <= ref.Token.Column+len(ref.Token.Literal) {
+			return ref, true
+		}
+	}
+	return Reference{}, false
+}
+
+// ReferencesTo returns every reference to definition in source order.
+func (a *Analysis) ReferencesTo(definition *Definition) []Reference {
+	refs := []Reference{}
+	for _, ref := range a.References {
+		if ref.Definition == definition {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// VisibleAt returns the definitions that code at line and column can refer
+// to: those made earlier in the scopes enclosing it, innermost first, and the
+// builtins.
+func (a *Analysis) VisibleAt(line int, column int) []*Definition {
+	visible := []*Definition{}
+	seen := map[string]bool{}
+
+	for i := len(a.scopes) - 1; i >= 0; i-- {
+		s := a.scopes[i]
+		if s.outer != nil && !(before(s.start, line, column) && !before(s.end, line, column)) {
+			continue
+		}
+
+		// Walk backwards so that a redefined name offers its latest binding.
+		for j := len(a.Definitions) - 1; j >= 0; j-- {
+			definition := a.Definitions[j]
+			if definition.scope != s || seen[definition.Name] || !before(definition.Token, line, column) {
+				continue
+			}
+			seen[definition.Name] = true
+			visible = append(visible, definition)
+		}
+	}
+
+	for _, builtin := range a.builtins {
+		if !seen[builtin.Name] {
+			visible = append(visible, builtin)
+		}
+	}
+	return visible
+}
+
+// before reports whether tok starts before line and column.
+func before(tok token.Token, line int, column int) bool {
+	return tok.Line < line || (tok.Line == line && tok.Column < column)
+}
+
+// matchingBraces maps each fn token to the brace that closes its body, so
+// that scopes know where they end.
+func matchingBraces(source string) map[token.Token]token.Token {
+	tokens := []token.Token{}
+	l := lexer.New(source)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		tokens = append(tokens, tok)
+	}
+
+	closers := map[token.Token]token.Token{}
+	for i, tok := range tokens {
+		if tok.Type != token.FUNCTION {
+			continue
+		}
+
+		depth := 0
+		for _, next := range tokens[i+1:] {
+			if next.Type == token.LBRACE {
+				depth++
+			} else if next.Type == token.RBRACE {
+				depth--
+				if depth == 0 {
+					closers[tok] = next
+					break
+				}
+			}
+		}
+
+		if _, ok := closers[tok]; !ok {
+			// An unclosed body runs to the end of the document.
+			closers[tok] = token.Token{Line: int(^uint(0) >> 1)}
+		}
+	}
+	return closers
+}
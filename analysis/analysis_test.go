@@ -0,0 +1,113 @@
+package analysis
+
+import (
+	"strings"
+	"testing"
+)
+
+const testSource = `let add = fn(a, b) { a + b };
+let total = add(1, 2);
+let outer = fn(x) {
+  let inner = x * 2;
+  inner + total
+};
+`
+
+func TestAnalyzeReferences(t *testing.T) {
+	a := Analyze(testSource)
+	if len(a.Problems) != 0 {
+		t.Fatalf("unexpected problems: %v", a.Problems)
+	}
+
+	tests := []struct {
+		line         int
+		column       int
+		expectedName string
+		expectedRefs int
+	}{
+		{1, 5, "add", 2},
+		{2, 13, "add", 2},
+		{1, 22, "a", 2},
+		{5, 11, "total", 2},
+		{4, 15, "x", 2},
+		{5, 3, "inner", 2},
+	}
+
+	for _, tt := range tests {
+		ref, ok := a.ReferenceAt(tt.line, tt.column)
+		if !ok {
+			t.Errorf("no reference at %d:%d", tt.line, tt.column)
+			continue
+		}
+		if ref.Definition.Name != tt.expectedName {
+			t.Errorf("wrong reference at %d:%d. want=%s, got=%s",
+				tt.line, tt.column, tt.expectedName, ref.Definition.Name)
+		}
+		if refs := a.ReferencesTo(ref.Definition); len(refs) != tt.expectedRefs {
+			t.Errorf("wrong number of references to %s. want=%d, got=%d",
+				tt.expectedName, tt.expectedRefs, len(refs))
+		}
+	}
+}
+
+func TestAnalyzeVisible(t *testing.T) {
+	a := Analyze(testSource)
+
+	tests := []struct {
+		line       int
+		column     int
+		visible    []string
+		notVisible []string
+	}{
+		{1, 1, []string{"len", "out"}, []string{"add", "total", "inner"}},
+		{2, 13, []string{"add", "total"}, []string{"outer", "a", "inner"}},
+		{5, 3, []string{"inner", "x", "total", "outer", "add"}, []string{"a", "b"}},
+		{7, 1, []string{"outer"}, []string{"inner", "x"}},
+	}
+
+	for _, tt := range tests {
+		names := map[string]bool{}
+		for _, definition := range a.VisibleAt(tt.line, tt.column) {
+			names[definition.Name] = true
+		}
+		for _, name := range tt.visible {
+			if !names[name] {
+				t.Errorf("%s not visible at %d:%d", name, tt.line, tt.column)
+			}
+		}
+		for _, name := range tt.notVisible {
+			if names[name] {
+				t.Errorf("%s visible at %d:%d", name, tt.line, tt.column)
+			}
+		}
+	}
+}
+
+func TestAnalyzeProblems(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedLine   int
+		expectedColumn int
+		expectedPrefix string
+	}{
+		{"let x = 1;\nlet = 2;", 2, 5, "expected next token to be IDENTIFER"},
+		{"let x = 1;\nx + y;", 2, 5, "undefined variable y"},
+	}
+
+	for _, tt := range tests {
+		a := Analyze(tt.input)
+		if len(a.Problems) == 0 {
+			t.Errorf("%q: expected problems", tt.input)
+			continue
+		}
+
+		problem := a.Problems[0]
+		if !strings.HasPrefix(problem.Message, tt.expectedPrefix) {
+			t.Errorf("%q: wrong message. want prefix %q, got=%q", tt.input, tt.expectedPrefix, problem.Message)
+		}
+		if problem.Token.Line != tt.expectedLine || problem.Token.Column != tt.expectedColumn {
+			t.Errorf("%q: wrong position. want=%d:%d, got=%d:%d", tt.input,
+				tt.expectedLine, tt.expectedColumn, problem.Token.Line, problem.Token.Column)
+		}
+	}
+}
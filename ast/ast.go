@@ -3,6 +3,7 @@ package ast
 import (
 	"bytes"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/mislavperi/adl-lang/token"
@@ -116,6 +117,15 @@ type IntegerLiteral struct {
 func (il *IntegerLiteral) isExpression()  {}
 func (il *IntegerLiteral) String() string { return il.Token.Literal }
 
+// FloatLiteral represents a floating point literal node in the AST.
+type FloatLiteral struct {
+	BaseNode
+	Value float64
+}
+
+func (fl *FloatLiteral) isExpression()  {}
+func (fl *FloatLiteral) String() string { return fl.Token.Literal }
+
 // PrefixExpression represents a prefix expression node in the AST.
 type PrefixExpression struct {
 	BaseNode
@@ -255,17 +265,58 @@ func (ie *IndexExpression) String() string {
 	return fmt.Sprintf("(%s[%s])", ie.Left.String(), ie.Index.String())
 }
 
+// SliceExpression represents a slice operation in the AST. Start and End are
+// nil when they are left out, as in arr[:2] or arr[1:].
+type SliceExpression struct {
+	BaseNode
+	Left  Expression
+	Start Expression
+	End   Expression
+}
+
+func (se *SliceExpression) isExpression() {}
+func (se *SliceExpression) String() string {
+	var start, end string
+	if se.Start != nil {
+		start = se.Start.String()
+	}
+	if se.End != nil {
+		end = se.End.String()
+	}
+	return fmt.Sprintf("(%s[%s:%s])", se.Left.String(), start, end)
+}
+
 // HashLiteral represents a hash map literal in the AST.
 type HashLiteral struct {
 	BaseNode
 	Pairs map[Expression]Expression
+
+	// Keys lists the keys of Pairs in source order.
+	Keys []Expression
 }
 
 func (hl *HashLiteral) isExpression() {}
 func (hl *HashLiteral) String() string {
 	pairs := make([]string, 0, len(hl.Pairs))
-	for key, value := range hl.Pairs {
-		pairs = append(pairs, fmt.Sprintf("%s:%s", key.String(), value.String()))
+	for _, key := range hl.OrderedKeys() {
+		pairs = append(pairs, fmt.Sprintf("%s:%s", key.String(), hl.Pairs[key].String()))
 	}
 	return fmt.Sprintf("{%s}", strings.Join(pairs, ", "))
 }
+
+// OrderedKeys returns the keys in source order. Literals built without Keys
+// have their keys sorted by their text instead.
+func (hl *HashLiteral) OrderedKeys() []Expression {
+	if len(hl.Keys) == len(hl.Pairs) {
+		return hl.Keys
+	}
+
+	keys := make([]Expression, 0, len(hl.Pairs))
+	for key := range hl.Pairs {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i int, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+	return keys
+}
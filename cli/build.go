@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mislavperi/adl-lang/compiler"
+	"github.com/mislavperi/adl-lang/marshal"
+)
+
+func runBuild(env *environment, args []string) int {
+	flags := env.newFlagSet("build", "[flags] file.adl")
+	output := flags.String("o", "", "write bytecode to `file` instead of file"+marshal.Extension)
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	if flags.NArg() != 1 || !strings.HasSuffix(flags.Arg(0), ".adl") {
+		flags.Usage()
+		return ExitUsage
+	}
+
+	name := flags.Arg(0)
+	content, err := os.ReadFile(name)
+	if err != nil {
+		fmt.Fprintf(env.stderr, "adl build: %s\n", err)
+		return ExitFailure
+	}
+
+	program, ok := env.parse(name, string(content))
+	if !ok {
+		return ExitFailure
+	}
+
+	comp := compiler.New()
+	if err := comp.Compile(program); err != nil {
+		fmt.Fprintf(env.stderr, "%s: compilation failed: %s\n", name, err)
+		return ExitFailure
+	}
+
+	target := *output
+	if target == "" {
+		target = strings.TrimSuffix(name, ".adl") + marshal.Extension
+	}
+
+	file, err := os.Create(target)
+	if err != nil {
+		fmt.Fprintf(env.stderr, "adl build: %s\n", err)
+		return ExitFailure
+	}
+	defer file.Close()
+
+	if err := marshal.WriteBytecode(file, comp.Bytecode()); err != nil {
+		fmt.Fprintf(env.stderr, "adl build: %s\n", err)
+		return ExitFailure
+	}
+
+	return ExitOK
+}
@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mislavperi/adl-lang/analysis"
+	"github.com/mislavperi/adl-lang/compiler"
+	"github.com/mislavperi/adl-lang/lint"
+)
+
+func runCheck(env *environment, args []string) int {
+	flags := env.newFlagSet("check", "[flags] [files or directories]")
+	quiet := flags.Bool("q", false, "only set the exit status, do not print problems")
+	disable := flags.String("disable", "", "comma separated `rules` not to report")
+	listRules := flags.Bool("rules", false, "list the rules and exit")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	if *listRules {
+		for _, rule := range lint.Rules {
+			fmt.Fprintf(env.stdout, "%-14s %s\n", rule.Name, rule.Summary)
+		}
+		return ExitOK
+	}
+
+	disabled, err := lint.ParseRules(*disable)
+	if err != nil {
+		fmt.Fprintf(env.stderr, "adl check: %s\n", err)
+		return ExitUsage
+	}
+	config := lint.Config{Disabled: disabled}
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	files, err := collectFiles(paths, func(name string) bool { return strings.HasSuffix(name, ".adl") })
+	if err != nil {
+		fmt.Fprintf(env.stderr, "adl check: %s\n", err)
+		return ExitFailure
+	}
+
+	report := env
+	if *quiet {
+		report = &environment{stdin: env.stdin, stdout: io.Discard, stderr: io.Discard}
+	}
+
+	failed := false
+	for _, name := range files {
+		content, err := os.ReadFile(name)
+		if err != nil {
+			fmt.Fprintf(report.stderr, "adl check: %s\n", err)
+			failed = true
+			continue
+		}
+
+		program, ok := report.parse(name, string(content))
+		if !ok {
+			failed = true
+			continue
+		}
+
+		if err := compiler.New().Compile(program); err != nil {
+			fmt.Fprintf(report.stderr, "%s: %s\n", name, err)
+			failed = true
+			continue
+		}
+
+		for _, warning := range lint.Check(analysis.Analyze(string(content)), config) {
+			fmt.Fprintf(report.stderr, "%s:%s\n", name, warning)
+			failed = true
+		}
+	}
+
+	if failed {
+		return ExitFailure
+	}
+	return ExitOK
+}
@@ -0,0 +1,233 @@
+// Package cli implements the adl command and its subcommands.
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mislavperi/adl-lang/ast"
+	"github.com/mislavperi/adl-lang/lexer"
+	"github.com/mislavperi/adl-lang/marshal"
+	"github.com/mislavperi/adl-lang/parser"
+	"github.com/mislavperi/adl-lang/representation"
+)
+
+// Exit codes returned by Main.
+const (
+	ExitOK      = 0
+	ExitFailure = 1
+	ExitUsage   = 2
+)
+
+const Banner = "Hello! This is the ADl programming language!"
+
+// environment holds the streams a command reads from and writes to.
+type environment struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+type command struct {
+	name    string
+	summary string
+	run     func(env *environment, args []string) int
+}
+
+var commands = []*command{
+	{"run", "run an ADL program", runRun},
+	{"repl", "start an interactive session", runRepl},
+	{"build", "compile a program to bytecode", runBuild},
+	{"fmt", "format ADL source files", runFmt},
+	{"check", "report errors in ADL source files", runCheck},
+	{"test", "run *_test.adl files", runTest},
+	{"lsp", "start the language server on stdin and stdout", runLsp},
+	{"version", "print the adl version", runVersion},
+}
+
+// Main runs the adl command with the given arguments, excluding the program
+// name, and returns the process exit code.
+func Main(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	env := &environment{stdin: stdin, stdout: stdout, stderr: stderr}
+
+	if len(args) == 0 {
+		return runRepl(env, args)
+	}
+
+	switch name := args[0]; {
+	case name == "help" || name == "-h" || name == "-help" || name == "--help":
+		printUsage(stdout)
+		return ExitOK
+	case name == "--version":
+		return runVersion(env, nil)
+	case isSourceFile(name) || strings.HasPrefix(name, "-"):
+		// `adl file.adl` and `adl -e expr` are shorthands for `adl run`.
+		return runRun(env, args)
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(env, args[1:])
+		}
+	}
+
+	fmt.Fprintf(stderr, "adl: unknown command %q\n", args[0])
+	printUsage(stderr)
+	return ExitUsage
+}
+
+func printUsage(out io.Writer) {
+	fmt.Fprintln(out, "Usage: adl <command> [flags] [arguments]")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "  %-8s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Run 'adl <command> -h' for the flags of a command.")
+}
+
+// newFlagSet creates the flag set for a subcommand, printing its usage to stderr.
+func (env *environment) newFlagSet(name string, arguments string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(env.stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(env.stderr, "Usage: adl %s %s\n", name, arguments)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// parseFlags parses args, returning the exit code to stop with when parsing
+// fails or help was requested.
+func parseFlags(flags *flag.FlagSet, args []string) (int, bool) {
+	err := flags.Parse(args)
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		return ExitOK, false
+	case err != nil:
+		return ExitUsage, false
+	default:
+		return ExitOK, true
+	}
+}
+
+// quietFlag registers -q and -quiet, which suppress the banner and value echo.
+func quietFlag(flags *flag.FlagSet) *bool {
+	quiet := flags.Bool("quiet", false, "suppress the banner and the echo of the last value")
+	flags.BoolVar(quiet, "q", false, "shorthand for -quiet")
+	return quiet
+}
+
+// strictFlag registers -strict, which makes indexing out of range an error.
+func strictFlag(flags *flag.FlagSet) *bool {
+	return flags.Bool("strict", false, "make indexing or slicing out of range an error instead of null")
+}
+
+// fileFlags registers -fs-root, -fs-read-only and -no-fs, which set the policy
+// of the file builtins. Scripts run from the command line are trusted, so by
+// default they can access every path.
+func fileFlags(flags *flag.FlagSet) func() *representation.FilePolicy {
+	roots := flags.String("fs-root", "", "comma separated `directories` the file builtins are limited to")
+	readOnly := flags.Bool("fs-read-only", false, "do not let the file builtins write or remove files")
+	disabled := flags.Bool("no-fs", false, "disable the file builtins")
+
+	return func() *representation.FilePolicy {
+		if *disabled {
+			return nil
+		}
+		policy := &representation.FilePolicy{ReadOnly: *readOnly}
+		for _, root := range strings.Split(*roots, ",") {
+			if root = strings.TrimSpace(root); root != "" {
+				policy.Roots = append(policy.Roots, root)
+			}
+		}
+		return policy
+	}
+}
+
+// seedFlag registers -seed, which makes the random builtins repeat the same
+// sequence on every run. Each call of the returned function starts a new
+// generator, or returns nil to seed from the clock when the flag is not set.
+func seedFlag(flags *flag.FlagSet) func() *rand.Rand {
+	seed := flags.Int64("seed", 0, "`seed` the random builtins to make runs reproducible")
+
+	return func() *rand.Rand {
+		set := false
+		flags.Visit(func(f *flag.Flag) {
+			set = set || f.Name == "seed"
+		})
+		if !set {
+			return nil
+		}
+		return rand.New(rand.NewSource(*seed))
+	}
+}
+
+// parse parses source, reporting any parser errors under name.
+func (env *environment) parse(name string, source string) (*ast.Program, bool) {
+	p := parser.New(lexer.New(source))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		printParserErrors(env.stderr, name, p.Errors())
+		return nil, false
+	}
+	return program, true
+}
+
+func printParserErrors(out io.Writer, name string, errors []string) {
+	fmt.Fprintf(out, "%s: Woops! We ran into some trouble here!\n", name)
+	fmt.Fprintln(out, " parser errors:")
+	for _, msg := range errors {
+		fmt.Fprintf(out, "\t%s\n", msg)
+	}
+}
+
+func isSourceFile(name string) bool {
+	ext := filepath.Ext(name)
+	return ext == ".adl" || ext == marshal.Extension
+}
+
+// collectFiles expands paths into the sorted list of files accepted by match,
+// walking directories recursively. Files named explicitly are always kept.
+func collectFiles(paths []string, match func(name string) bool) ([]string, error) {
+	files := []string{}
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		err = filepath.WalkDir(path, func(name string, entry os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() && name != path && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			if !entry.IsDir() && match(entry.Name()) {
+				files = append(files, name)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
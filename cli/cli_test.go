@@ -0,0 +1,289 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type cliTestCase struct {
+	args           []string
+	stdin          string
+	expectedCode   int
+	expectedStdout string
+}
+
+func TestRun(t *testing.T) {
+	t.Setenv("ADL_CLI_TEST", "set")
+
+	tests := []cliTestCase{
+		{[]string{"run", "-e", "1 + 2"}, "", ExitOK, "3\n"},
+		{[]string{"-e", "1 + 2"}, "", ExitOK, "3\n"},
+		{[]string{"run", "-q", "-e", "1 + 2"}, "", ExitOK, ""},
+		{[]string{"run", "-engine=eval", "-e", `"a" + "b"`}, "", ExitOK, "ab\n"},
+		{[]string{"run"}, "let x = 5; x * 5", ExitOK, "25\n"},
+		{[]string{"run", "-"}, "let x = 5;", ExitOK, ""},
+		{[]string{"run", "-e", "let"}, "", ExitFailure, ""},
+		{[]string{"run", "-e", "undefined"}, "", ExitFailure, ""},
+		{[]string{"run", "-engine=jit", "-e", "1"}, "", ExitUsage, ""},
+		{[]string{"run", "-unknown"}, "", ExitUsage, ""},
+		{[]string{"run", "file.txt"}, "", ExitFailure, ""},
+		{[]string{"run", "-e", "args()", "a", "b"}, "", ExitOK, "[a, b]\n"},
+		{[]string{"run", "-", "x"}, "args()", ExitOK, "[x]\n"},
+		{[]string{"run", "-e", `env("ADL_CLI_TEST")`}, "", ExitOK, "set\n"},
+		{[]string{"run", "-e", `env("ADL_CLI_TEST_UNSET")`}, "", ExitOK, "null\n"},
+		{[]string{"run", "-e", "exit(0); 1"}, "", ExitOK, ""},
+		{[]string{"run", "-e", "let f = fn() { exit(3) }; f(); 1"}, "", 3, ""},
+		{[]string{"run", "-engine=eval", "-e", "let f = fn() { exit(4) }; f(); 1"}, "", 4, ""},
+		{[]string{"repl", "-q"}, "exit(3)\n1\n", 3, ">>"},
+		{[]string{"repl", "-q"}, ":quit\n", ExitOK, ">>"},
+		{[]string{"repl", "-q"}, "1\n", ExitOK, ">>1\n>>"},
+		{[]string{"repl", "-q"}, "read_line()\nhello\n", ExitOK, ">>hello\n>>"},
+	}
+
+	runCliTests(t, tests)
+}
+
+func TestOutput(t *testing.T) {
+	runCliTests(t, []cliTestCase{
+		{[]string{"run", "-q", "-e", `print("a", 1); printf("|%03d|", 7); out(2)`}, "", ExitOK, "a1|007|2\n"},
+		{[]string{"run", "-q", "-e", `printf("%d\t%s\n", 1, "\"a\"")`}, "", ExitOK, "1\t\"a\"\n"},
+		{[]string{"run", "-q", "-e", `out(upper(input("? ")))`, "x"}, "abc\n", ExitOK, "? ABC\n"},
+	})
+}
+
+func TestFileFlags(t *testing.T) {
+	dir := t.TempDir()
+	data := writeFile(t, dir, "data.txt", "content")
+	read := `read_file("` + data + `")`
+
+	runCliTests(t, []cliTestCase{
+		{[]string{"run", "-e", read}, "", ExitOK, "content\n"},
+		{[]string{"run", "-fs-root", dir, "-e", read}, "", ExitOK, "content\n"},
+		{[]string{"run", "-fs-root", t.TempDir(), "-e", read}, "", ExitFailure, ""},
+		{[]string{"run", "-no-fs", "-e", read}, "", ExitFailure, ""},
+		{[]string{"run", "-fs-read-only", "-e", `remove("` + data + `")`}, "", ExitFailure, ""},
+	})
+}
+
+func TestSeed(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "random_test.adl", `
+test("a", fn() { out(random_int(1, 1000000)) });
+test("b", fn() { out(random_int(1, 1000000)) });
+`)
+
+	output := func(args ...string) string {
+		var stdout, stderr bytes.Buffer
+		if code := Main(args, strings.NewReader(""), &stdout, &stderr); code != ExitOK {
+			t.Fatalf("adl %v: exit code %d (stderr=%q)", args, code, stderr.String())
+		}
+		return stdout.String()
+	}
+
+	program := "shuffle(range(0, 20))"
+	if a, b := output("run", "-seed", "7", "-e", program), output("run", "--seed=7", "-e", program); a != b {
+		t.Errorf("runs with the same seed differ: %q and %q", a, b)
+	}
+	if a, b := output("run", "-seed", "7", "-e", program), output("run", "-seed", "8", "-e", program); a == b {
+		t.Errorf("runs with different seeds agree: %q", a)
+	}
+
+	// Every seeded test starts from the same state.
+	lines := strings.Split(output("test", "-seed", "3", dir), "\n")
+	if len(lines) < 3 || lines[0] != lines[1] || lines[2] != "2 passed, 0 failed" {
+		t.Errorf("seeded tests drew different numbers: %q", lines)
+	}
+}
+
+func TestStrict(t *testing.T) {
+	for _, engineName := range []string{"vm", "eval"} {
+		var stdout, stderr bytes.Buffer
+		if code := Main([]string{"run", "-engine", engineName, "-e", "[1, 2][5]"}, strings.NewReader(""), &stdout, &stderr); code != ExitOK || stdout.String() != "null\n" {
+			t.Errorf("%s: lenient run exited with %d, printing %q", engineName, code, stdout.String())
+		}
+
+		stdout.Reset()
+		code := Main([]string{"run", "-strict", "-engine", engineName, "-e", "[1, 2][5]"}, strings.NewReader(""), &stdout, &stderr)
+		if code != ExitFailure || !strings.Contains(stderr.String(), "index out of range: 5 with length 2") {
+			t.Errorf("%s: strict run exited with %d (stderr=%q)", engineName, code, stderr.String())
+		}
+	}
+}
+
+func TestCommands(t *testing.T) {
+	tests := []cliTestCase{
+		{[]string{"version", "-short"}, "", ExitOK, Version + "\n"},
+		{[]string{"repl", "-q"}, "1 + 1\n", ExitOK, ">>2\n>>"},
+		{[]string{"unknown"}, "", ExitUsage, ""},
+		{[]string{"run", "-h"}, "", ExitOK, ""},
+	}
+
+	runCliTests(t, tests)
+}
+
+func TestBuildAndRunBytecode(t *testing.T) {
+	dir := t.TempDir()
+	source := writeFile(t, dir, "main.adl", `let double = fn(x) { x * 2 }; double(21);`)
+
+	runCliTests(t, []cliTestCase{
+		{[]string{"build", source}, "", ExitOK, ""},
+		{[]string{"run", filepath.Join(dir, "main.adlc")}, "", ExitOK, "42\n"},
+		{[]string{"run", "-engine=eval", filepath.Join(dir, "main.adlc")}, "", ExitUsage, ""},
+	})
+}
+
+func TestCheckAndTest(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "good_test.adl", `let x = 1; x + 1;`)
+	broken := writeFile(t, dir, "broken_test.adl", `let x = 1; x + y;`)
+
+	runCliTests(t, []cliTestCase{
+		{[]string{"check", "-q", dir}, "", ExitFailure, ""},
+		{[]string{"test", dir}, "", ExitFailure, ""},
+		{[]string{"test", "-run", "good", dir}, "", ExitOK, "1 passed, 0 failed\n"},
+	})
+
+	if err := os.Remove(broken); err != nil {
+		t.Fatal(err)
+	}
+
+	runCliTests(t, []cliTestCase{
+		{[]string{"check", dir}, "", ExitOK, ""},
+		{[]string{"test", "-engine=eval", dir}, "", ExitOK, "1 passed, 0 failed\n"},
+	})
+}
+
+func TestTestCases(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "cases_test.adl", `
+let double = fn(x) { x * 2 };
+test("double", fn() { assert_eq(double(2), 4) });
+test("broken", fn() { assert_eq(double(2), 5) });
+test("truthy", fn() { assert(double(0) == 0, "zero") });
+`)
+
+	for _, engineName := range []string{"-engine=vm", "-engine=eval"} {
+		runCliTests(t, []cliTestCase{
+			{[]string{"test", engineName, dir}, "", ExitFailure, ""},
+			{[]string{"test", engineName, "-run", "double|truthy", dir}, "", ExitOK, "2 passed, 0 failed\n"},
+		})
+	}
+
+	var stdout, stderr bytes.Buffer
+	Main([]string{"test", "-run", "broken", dir}, strings.NewReader(""), &stdout, &stderr)
+	for _, want := range []string{"FAIL\t" + filepath.Join(dir, "cases_test.adl") + ": broken\t", "\twant: 5\n\t got: 4\n", "0 passed, 1 failed\n"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("output does not contain %q:\n%s", want, stdout.String())
+		}
+	}
+}
+
+func TestTestsRunInIsolation(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "setup_test.adl", `
+out("setup");
+let data = [1, 2];
+test("first", fn() { let more = push(data, 3); assert_eq(len(more), 3) });
+test("second", fn() { assert_eq(data, [1, 2]) });
+test("exits", fn() { exit(0) });
+test("fails", fn() { exit(2) });
+`)
+
+	// Tests that fail deep in recursion come before one that passes.
+	deep := "let down = fn(n) { if (n == 0) { assert(false) } else { down(n - 1) } };\n"
+	for i := 0; i < 30; i++ {
+		deep += "test(\"deep\", fn() { down(50) });\n"
+	}
+	deep += "test(\"ok\", fn() { assert_eq(1, 1) });\n"
+	writeFile(t, dir, "deep_test.adl", deep)
+
+	for _, engineName := range []string{"-engine=vm", "-engine=eval"} {
+		runCliTests(t, []cliTestCase{
+			{[]string{"test", engineName, "-run", "first|second|exits", dir}, "", ExitOK, "setup\nsetup\nsetup\n3 passed, 0 failed\n"},
+			{[]string{"test", engineName, "-run", "fails", dir}, "", ExitFailure, ""},
+			{[]string{"test", engineName, "-run", "^ok$", dir}, "", ExitOK, "1 passed, 0 failed\n"},
+		})
+
+		var stdout, stderr bytes.Buffer
+		Main([]string{"test", engineName, "-run", "deep", dir}, strings.NewReader(""), &stdout, &stderr)
+		if !strings.HasSuffix(stdout.String(), "1 passed, 30 failed\n") || strings.Contains(stdout.String(), "stack overflow") {
+			t.Errorf("%s: failing tests affected the ones after them:\n%s", engineName, stdout.String())
+		}
+	}
+}
+
+func TestCheckWarnings(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "lint.adl", "let unused = 1;\nlet f = fn(a) { a };\nf(1, 2);\n")
+
+	runCliTests(t, []cliTestCase{
+		{[]string{"check", dir}, "", ExitFailure, ""},
+		{[]string{"check", "-disable=unused,arity", dir}, "", ExitOK, ""},
+		{[]string{"check", "-disable=typo", dir}, "", ExitUsage, ""},
+	})
+
+	var stdout, stderr bytes.Buffer
+	Main([]string{"check", dir}, strings.NewReader(""), &stdout, &stderr)
+	for _, want := range []string{"lint.adl:1:5: `unused` is never used (unused)", "lint.adl:3:2: `f` takes 1 arguments, called with 2 (arity)"} {
+		if !strings.Contains(stderr.String(), want) {
+			t.Errorf("check output does not contain %q:\n%s", want, stderr.String())
+		}
+	}
+}
+
+func TestFmt(t *testing.T) {
+	dir := t.TempDir()
+	messy := writeFile(t, dir, "messy.adl", "let x=1 // one\nx+1")
+	writeFile(t, dir, "tidy.adl", "let y = 2;\n")
+
+	runCliTests(t, []cliTestCase{
+		{[]string{"fmt"}, "let a=[1,2]", ExitOK, "let a = [1, 2];\n"},
+		{[]string{"fmt", "-check"}, "let a = 1;\n", ExitOK, ""},
+		{[]string{"fmt", "-w"}, "", ExitUsage, ""},
+		{[]string{"fmt"}, "let = 1", ExitFailure, ""},
+		{[]string{"fmt", messy}, "", ExitOK, "let x = 1; // one\nx + 1;\n"},
+		{[]string{"fmt", "--check", dir}, "", ExitFailure, ""},
+		{[]string{"fmt", "-w", dir}, "", ExitOK, ""},
+		{[]string{"fmt", "--check", dir}, "", ExitOK, ""},
+	})
+
+	content, err := os.ReadFile(messy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "let x = 1; // one\nx + 1;\n" {
+		t.Errorf("fmt -w did not rewrite the file, got %q", content)
+	}
+}
+
+func runCliTests(t *testing.T, tests []cliTestCase) {
+	t.Helper()
+
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		code := Main(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr)
+
+		if code != tt.expectedCode {
+			t.Errorf("adl %v: wrong exit code. want=%d, got=%d (stderr=%q)",
+				tt.args, tt.expectedCode, code, stderr.String())
+		}
+
+		if tt.expectedCode == ExitOK && stdout.String() != tt.expectedStdout {
+			t.Errorf("adl %v: wrong output. want=%q, got=%q",
+				tt.args, tt.expectedStdout, stdout.String())
+		}
+	}
+}
+
+func writeFile(t *testing.T, dir string, name string, content string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
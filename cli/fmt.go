@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mislavperi/adl-lang/format"
+)
+
+func runFmt(env *environment, args []string) int {
+	flags := env.newFlagSet("fmt", "[flags] [files or directories]")
+	write := flags.Bool("w", false, "write the result to the source files instead of stdout")
+	check := flags.Bool("check", false, "list files that are not formatted and fail if there are any")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	if flags.NArg() == 0 {
+		if *write {
+			fmt.Fprintln(env.stderr, "adl fmt: cannot use -w with standard input")
+			return ExitUsage
+		}
+		return env.formatStdin(*check)
+	}
+
+	files, err := collectFiles(flags.Args(), func(name string) bool { return strings.HasSuffix(name, ".adl") })
+	if err != nil {
+		fmt.Fprintf(env.stderr, "adl fmt: %s\n", err)
+		return ExitFailure
+	}
+
+	code := ExitOK
+	for _, name := range files {
+		info, err := os.Stat(name)
+		if err != nil {
+			fmt.Fprintf(env.stderr, "adl fmt: %s\n", err)
+			code = ExitFailure
+			continue
+		}
+
+		content, err := os.ReadFile(name)
+		if err != nil {
+			fmt.Fprintf(env.stderr, "adl fmt: %s\n", err)
+			code = ExitFailure
+			continue
+		}
+
+		formatted, ok := env.format(name, content)
+		if !ok {
+			code = ExitFailure
+			continue
+		}
+
+		switch {
+		case *check:
+			if !bytes.Equal(content, formatted) {
+				fmt.Fprintln(env.stdout, name)
+				code = ExitFailure
+			}
+		case *write:
+			if bytes.Equal(content, formatted) {
+				continue
+			}
+			if err := os.WriteFile(name, formatted, info.Mode().Perm()); err != nil {
+				fmt.Fprintf(env.stderr, "adl fmt: %s\n", err)
+				code = ExitFailure
+			}
+		default:
+			env.stdout.Write(formatted)
+		}
+	}
+	return code
+}
+
+func (env *environment) formatStdin(check bool) int {
+	content, err := io.ReadAll(env.stdin)
+	if err != nil {
+		fmt.Fprintf(env.stderr, "adl fmt: %s\n", err)
+		return ExitFailure
+	}
+
+	formatted, ok := env.format("<stdin>", content)
+	if !ok {
+		return ExitFailure
+	}
+
+	if check {
+		if !bytes.Equal(content, formatted) {
+			fmt.Fprintln(env.stdout, "<stdin>")
+			return ExitFailure
+		}
+		return ExitOK
+	}
+
+	env.stdout.Write(formatted)
+	return ExitOK
+}
+
+// format formats content, reporting why it could not under name.
+func (env *environment) format(name string, content []byte) ([]byte, bool) {
+	formatted, err := format.Source(content)
+
+	var syntaxErr *format.Error
+	if errors.As(err, &syntaxErr) {
+		printParserErrors(env.stderr, name, syntaxErr.Errors)
+		return nil, false
+	}
+	if err != nil {
+		fmt.Fprintf(env.stderr, "%s: %s\n", name, err)
+		return nil, false
+	}
+	return formatted, true
+}
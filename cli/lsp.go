@@ -0,0 +1,32 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mislavperi/adl-lang/lsp"
+)
+
+func runLsp(env *environment, args []string) int {
+	flags := env.newFlagSet("lsp", "")
+	// Editors commonly pass --stdio; it is the only transport, so accept it.
+	flags.Bool("stdio", true, "communicate over stdin and stdout")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return ExitUsage
+	}
+
+	err := lsp.NewServer(env.stdin, env.stdout, Version).Serve()
+	if errors.Is(err, lsp.ErrNoShutdown) {
+		return ExitFailure
+	}
+	if err != nil {
+		fmt.Fprintf(env.stderr, "adl lsp: %s\n", err)
+		return ExitFailure
+	}
+	return ExitOK
+}
@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mislavperi/adl-lang/repl"
+	"github.com/mislavperi/adl-lang/representation"
+)
+
+func runRepl(env *environment, args []string) int {
+	flags := env.newFlagSet("repl", "[flags]")
+	quiet := quietFlag(flags)
+	filePolicy := fileFlags(flags)
+	random := seedFlag(flags)
+	strict := strictFlag(flags)
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return ExitUsage
+	}
+
+	if !*quiet {
+		fmt.Fprintln(env.stdout, Banner)
+		fmt.Fprintln(env.stdout, "Feel free to type in some commands")
+	}
+
+	host := &representation.Host{
+		Args:      []string{},
+		LookupEnv: os.LookupEnv,
+		Stdout:    env.stdout,
+		FS:        filePolicy(),
+		Rand:      random(),
+		Strict:    *strict,
+	}
+	return repl.StartWithHost(env.stdin, env.stdout, host)
+}
@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mislavperi/adl-lang/engine"
+	"github.com/mislavperi/adl-lang/marshal"
+	"github.com/mislavperi/adl-lang/representation"
+)
+
+func runRun(env *environment, args []string) int {
+	flags := env.newFlagSet("run", "[flags] [file.adl | file.adlc | -] [arguments]")
+	engineName := flags.String("engine", engine.VM, "execution engine: vm or eval")
+	expr := flags.String("e", "", "evaluate `expr` instead of reading a file")
+	quiet := quietFlag(flags)
+	filePolicy := fileFlags(flags)
+	random := seedFlag(flags)
+	strict := strictFlag(flags)
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	name, content, scriptArgs, err := env.readProgram(*expr, flags.Args())
+	if err != nil {
+		fmt.Fprintf(env.stderr, "adl run: %s\n", err)
+		return ExitFailure
+	}
+
+	host := &representation.Host{
+		Args:      scriptArgs,
+		LookupEnv: os.LookupEnv,
+		Stdout:    env.stdout,
+		Stdin:     env.stdin,
+		FS:        filePolicy(),
+		Rand:      random(),
+		Strict:    *strict,
+	}
+	machine, err := engine.New(*engineName, host)
+	if err != nil {
+		fmt.Fprintf(env.stderr, "adl run: %s\n", err)
+		return ExitUsage
+	}
+
+	var result representation.Representation
+	if marshal.IsBytecode(content) {
+		vmEngine, ok := machine.(*engine.VMEngine)
+		if !ok {
+			fmt.Fprintf(env.stderr, "adl run: %s: compiled bytecode requires the vm engine\n", name)
+			return ExitUsage
+		}
+
+		bytecode, err := marshal.ReadBytecode(bytes.NewReader(content))
+		if err != nil {
+			fmt.Fprintf(env.stderr, "adl run: %s: %s\n", name, err)
+			return ExitFailure
+		}
+		result, err = vmEngine.RunBytecode(bytecode)
+		if err != nil {
+			return env.reportRunError(name, err)
+		}
+	} else {
+		program, ok := env.parse(name, string(content))
+		if !ok {
+			return ExitFailure
+		}
+		result, err = machine.Run(program)
+		if err != nil {
+			return env.reportRunError(name, err)
+		}
+	}
+
+	if !*quiet && result != nil {
+		fmt.Fprintln(env.stdout, result.Inspect())
+	}
+
+	return ExitOK
+}
+
+// reportRunError prints a failed run and returns its exit code, which is the
+// requested status when the script called exit.
+func (env *environment) reportRunError(name string, err error) int {
+	var exit *representation.Exit
+	if errors.As(err, &exit) {
+		return exit.Code
+	}
+
+	fmt.Fprintf(env.stderr, "%s: execution failed: %s\n", name, err)
+	return ExitFailure
+}
+
+// readProgram returns the program to run and the arguments passed to it. The
+// program is the inline expression if one was given, otherwise the named
+// file, or standard input for "-" or no file.
+func (env *environment) readProgram(expr string, args []string) (string, []byte, []string, error) {
+	if expr != "" {
+		return "<expr>", []byte(expr), args, nil
+	}
+
+	if len(args) == 0 || args[0] == "-" {
+		if len(args) > 0 {
+			args = args[1:]
+		}
+		content, err := io.ReadAll(env.stdin)
+		return "<stdin>", content, args, err
+	}
+
+	name := args[0]
+	if !isSourceFile(name) {
+		return "", nil, nil, fmt.Errorf("invalid file extension for %s, expected .adl or %s", name, marshal.Extension)
+	}
+
+	content, err := os.ReadFile(name)
+	return name, content, args[1:], err
+}
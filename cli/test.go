@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mislavperi/adl-lang/ast"
+	"github.com/mislavperi/adl-lang/engine"
+	"github.com/mislavperi/adl-lang/representation"
+)
+
+// testFileSuffix marks the files discovered by `adl test`.
+const testFileSuffix = "_test.adl"
+
+func runTest(env *environment, args []string) int {
+	flags := env.newFlagSet("test", "[flags] [files or directories]")
+	engineName := flags.String("engine", engine.VM, "execution engine: vm or eval")
+	pattern := flags.String("run", "", "only run tests whose file path or name matches `regexp`")
+	verbose := flags.Bool("v", false, "report every test, not only failures")
+	filePolicy := fileFlags(flags)
+	random := seedFlag(flags)
+	strict := strictFlag(flags)
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	if _, err := engine.New(*engineName, nil); err != nil {
+		fmt.Fprintf(env.stderr, "adl test: %s\n", err)
+		return ExitUsage
+	}
+
+	filter, err := regexp.Compile(*pattern)
+	if err != nil {
+		fmt.Fprintf(env.stderr, "adl test: invalid -run pattern: %s\n", err)
+		return ExitUsage
+	}
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	files, err := collectFiles(paths, func(name string) bool { return strings.HasSuffix(name, testFileSuffix) })
+	if err != nil {
+		fmt.Fprintf(env.stderr, "adl test: %s\n", err)
+		return ExitFailure
+	}
+
+	newHost := func() *representation.Host {
+		return &representation.Host{LookupEnv: os.LookupEnv, Stdout: env.stdout, FS: filePolicy(), Rand: random(), Strict: *strict}
+	}
+
+	passed, failed := 0, 0
+	report := func(name string, start time.Time, err error) {
+		elapsed := time.Since(start).Seconds()
+		if err != nil {
+			failed++
+			message := strings.ReplaceAll(err.Error(), "\n", "\n\t")
+			fmt.Fprintf(env.stdout, "FAIL\t%s\t%.3fs\n\t%s\n", name, elapsed, message)
+			return
+		}
+
+		passed++
+		if *verbose {
+			fmt.Fprintf(env.stdout, "ok\t%s\t%.3fs\n", name, elapsed)
+		}
+	}
+
+	for _, name := range files {
+		content, err := os.ReadFile(name)
+		if err != nil {
+			report(name, time.Now(), err)
+			continue
+		}
+
+		program, ok := env.parse(name, string(content))
+		if !ok {
+			report(name, time.Now(), fmt.Errorf("parsing failed"))
+			continue
+		}
+
+		// The first run collects the names of the tests without running
+		// them. What the top level prints is shown again when each test runs
+		// it, so it is only shown for a file without tests.
+		start := time.Now()
+		var output bytes.Buffer
+		discovery := newHost()
+		discovery.Stdout = &output
+		tests := []string{}
+		err = runTestProgram(*engineName, discovery, program, func(host *representation.Host, test string, fn representation.Representation) representation.Representation {
+			tests = append(tests, test)
+			return nil
+		})
+
+		if err != nil || len(tests) == 0 {
+			// A file without tests passes when it runs to the end.
+			if filter.MatchString(name) {
+				env.stdout.Write(output.Bytes())
+				report(name, start, err)
+			}
+			continue
+		}
+
+		for index, test := range tests {
+			if !filter.MatchString(name) && !filter.MatchString(test) {
+				continue
+			}
+
+			start := time.Now()
+			report(name+": "+test, start, runTestCase(*engineName, newHost(), program, index))
+		}
+	}
+
+	fmt.Fprintf(env.stdout, "%d passed, %d failed\n", passed, failed)
+	if failed > 0 {
+		return ExitFailure
+	}
+	return ExitOK
+}
+
+// runTestCase runs a test file on a fresh engine, calling the body of the
+// test at index and skipping the others, so that every test starts from the
+// state the top level of the file sets up.
+func runTestCase(engineName string, host *representation.Host, program *ast.Program, index int) error {
+	seen, ran := 0, false
+	err := runTestProgram(engineName, host, program, func(host *representation.Host, test string, fn representation.Representation) representation.Representation {
+		seen++
+		if seen-1 != index {
+			return nil
+		}
+		ran = true
+		return host.Call(fn)
+	})
+
+	if err == nil && !ran {
+		return fmt.Errorf("test was not registered when the file ran again")
+	}
+	return err
+}
+
+// runTestProgram runs a test file on a fresh engine with host, handing every
+// call to the `test` builtin to register. Exiting with status 0 counts as
+// success.
+func runTestProgram(engineName string, host *representation.Host, program *ast.Program,
+	register func(host *representation.Host, name string, fn representation.Representation) representation.Representation) error {
+	host.Test = func(name string, fn representation.Representation) representation.Representation {
+		return register(host, name, fn)
+	}
+
+	machine, err := engine.New(engineName, host)
+	if err != nil {
+		return err
+	}
+
+	_, err = machine.Run(program)
+
+	var exit *representation.Exit
+	if errors.As(err, &exit) && exit.Code == 0 {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,28 @@
+package cli
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/mislavperi/adl-lang/marshal"
+)
+
+// Version is the version of the adl command.
+const Version = "0.1.0"
+
+func runVersion(env *environment, args []string) int {
+	flags := env.newFlagSet("version", "[flags]")
+	short := flags.Bool("short", false, "print only the version number")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	if *short {
+		fmt.Fprintln(env.stdout, Version)
+		return ExitOK
+	}
+
+	fmt.Fprintf(env.stdout, "adl version %s (bytecode v%d, %s %s/%s)\n",
+		Version, marshal.Version, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	return ExitOK
+}
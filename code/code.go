@@ -78,6 +78,12 @@ const (
 	OpClosure
 	OpCurrentClosure
 	OpGetFree
+	OpGetLocalWide
+	OpSetLocalWide
+	OpGetFreeWide
+	OpCallWide
+	OpClosureWide
+	OpSlice
 )
 
 type BytecodeDefinition struct {
@@ -117,6 +123,21 @@ var definitions = map[Opcode]*BytecodeDefinition{
 	OpClosure:        {"OpClosure", []int{2, 1}},
 	OpCurrentClosure: {"OpCurrentClosure", []int{}},
 	OpGetFree:        {"OpGetFree", []int{1}},
+	OpGetLocalWide:   {"OpGetLocalWide", []int{2}},
+	OpSetLocalWide:   {"OpSetLocalWide", []int{2}},
+	OpGetFreeWide:    {"OpGetFreeWide", []int{2}},
+	OpCallWide:       {"OpCallWide", []int{2}},
+	OpClosureWide:    {"OpClosureWide", []int{2, 2}},
+	OpSlice:          {"OpSlice", []int{}},
+}
+
+// wideVariants maps opcodes with one-byte operands to their two-byte counterparts.
+var wideVariants = map[Opcode]Opcode{
+	OpGetLocal: OpGetLocalWide,
+	OpSetLocal: OpSetLocalWide,
+	OpGetFree:  OpGetFreeWide,
+	OpCall:     OpCallWide,
+	OpClosure:  OpClosureWide,
 }
 
 // Lookup finds the definition for a given opcode.
@@ -127,7 +148,43 @@ func Lookup(op byte) (*BytecodeDefinition, error) {
 	return nil, fmt.Errorf("opcode %d undefined", op)
 }
 
-// Make creates an instruction from an opcode and its operands.
+// Fits reports whether every operand can be encoded in the width the opcode defines for it.
+func Fits(op Opcode, operands ...int) bool {
+	def, ok := definitions[op]
+	if !ok || len(operands) != len(def.OperandWidths) {
+		return false
+	}
+
+	for i, o := range operands {
+		if o < 0 || o >= 1<<(8*def.OperandWidths[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Widen returns the narrowest encoding of op that can hold the operands,
+// or an error if even the wide variant is too small.
+func Widen(op Opcode, operands ...int) (Opcode, error) {
+	if Fits(op, operands...) {
+		return op, nil
+	}
+
+	if wide, ok := wideVariants[op]; ok && Fits(wide, operands...) {
+		return wide, nil
+	}
+
+	name := fmt.Sprintf("%d", op)
+	if def, ok := definitions[op]; ok {
+		name = def.Name
+	}
+	return op, fmt.Errorf("operands %v out of range for %s", operands, name)
+}
+
+// Make creates an instruction from an opcode and its operands. Operands wider
+// than the definition allows are truncated, so callers should check Fits or
+// use Widen first.
 func Make(op Opcode, operands ...int) []byte {
 	def, ok := definitions[op]
 	if !ok {
@@ -12,6 +12,8 @@ func TestMake(t *testing.T) {
 		{OpAdd, []int{}, []byte{byte(OpAdd)}},
 		{OpGetLocal, []int{255}, []byte{byte(OpGetLocal), 255}},
 		{OpClosure, []int{65534, 255}, []byte{byte(OpClosure), 255, 254, 255}},
+		{OpGetLocalWide, []int{256}, []byte{byte(OpGetLocalWide), 1, 0}},
+		{OpClosureWide, []int{65534, 256}, []byte{byte(OpClosureWide), 255, 254, 1, 0}},
 	}
 
 	for _, tt := range tests {
@@ -67,6 +69,8 @@ func TestReadOperands(t *testing.T) {
 		{OpConstant, []int{65535}, 2},
 		{OpGetLocal, []int{255}, 1},
 		{OpClosure, []int{65535, 255}, 3},
+		{OpCallWide, []int{65535}, 2},
+		{OpClosureWide, []int{65535, 65535}, 4},
 	}
 
 	for _, tt := range tests {
@@ -89,3 +93,39 @@ func TestReadOperands(t *testing.T) {
 		}
 	}
 }
+
+func TestWiden(t *testing.T) {
+	tests := []struct {
+		op        Opcode
+		operands  []int
+		expected  Opcode
+		expectErr bool
+	}{
+		{OpGetLocal, []int{255}, OpGetLocal, false},
+		{OpGetLocal, []int{256}, OpGetLocalWide, false},
+		{OpSetLocal, []int{1000}, OpSetLocalWide, false},
+		{OpGetFree, []int{300}, OpGetFreeWide, false},
+		{OpCall, []int{256}, OpCallWide, false},
+		{OpClosure, []int{1, 255}, OpClosure, false},
+		{OpClosure, []int{1, 256}, OpClosureWide, false},
+		{OpGetLocal, []int{65536}, OpGetLocal, true},
+		{OpConstant, []int{65536}, OpConstant, true},
+		{OpGetBuiltin, []int{-1}, OpGetBuiltin, true},
+	}
+
+	for _, tt := range tests {
+		op, err := Widen(tt.op, tt.operands...)
+		if tt.expectErr {
+			if err == nil {
+				t.Errorf("expected error widening %d %v", tt.op, tt.operands)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if op != tt.expected {
+			t.Errorf("wrong opcode. want=%d, got=%d", tt.expected, op)
+		}
+	}
+}
@@ -1,9 +1,6 @@
 package compiler
 
 import (
-	"fmt"
-	"sort"
-
 	"github.com/mislavperi/adl-lang/ast"
 	"github.com/mislavperi/adl-lang/code"
 	"github.com/mislavperi/adl-lang/representation"
@@ -88,7 +85,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 		case "!":
 			c.emit(code.OpBang)
 		default:
-			return fmt.Errorf("unknown operator %s", node.Operator)
+			return errorAt(node.Token, "unknown operator %s", node.Operator)
 		}
 	case *ast.InfixExpression:
 		if node.Operator == "<" {
@@ -125,11 +122,18 @@ func (c *Compiler) Compile(node ast.Node) error {
 		case "!=":
 			c.emit(code.OpNotEqual)
 		default:
-			return fmt.Errorf("unknown operator %s: ", node.Operator)
+			return errorAt(node.Token, "unknown operator %s: ", node.Operator)
 		}
 	case *ast.IntegerLiteral:
 		integer := &representation.Integer{Value: node.Value}
-		c.emit(code.OpConstant, c.addConstant(integer))
+		if _, err := c.emitChecked(code.OpConstant, c.addConstant(integer)); err != nil {
+			return err
+		}
+	case *ast.FloatLiteral:
+		float := &representation.Float{Value: node.Value}
+		if _, err := c.emitChecked(code.OpConstant, c.addConstant(float)); err != nil {
+			return err
+		}
 	case *ast.Boolean:
 		if node.Value {
 			c.emit(code.OpTrue)
@@ -182,40 +186,41 @@ func (c *Compiler) Compile(node ast.Node) error {
 		if err := c.Compile(node.Value); err != nil {
 			return err
 		}
+		op := code.OpSetLocal
 		if symbolTable.Scope == symboltable.GlobalScope {
-			c.emit(code.OpSetGlobal, symbolTable.Index)
-		} else {
-			c.emit(code.OpSetLocal, symbolTable.Index)
+			op = code.OpSetGlobal
+		}
+		if _, err := c.emitChecked(op, symbolTable.Index); err != nil {
+			return err
 		}
 	case *ast.Identifier:
 		symbolTable, ok := c.symbolTable.Resolve(node.Value)
 		if !ok {
-			return fmt.Errorf("undefined variable %s", node.Value)
+			return errorAt(node.Token, "undefined variable %s", node.Value)
 		}
 
-		c.loadSymbol(symbolTable)
+		if err := c.loadSymbol(symbolTable); err != nil {
+			return err
+		}
 
 	case *ast.StringLiteral:
 		str := &representation.String{Value: node.Value}
-		c.emit(code.OpConstant, c.addConstant(str))
+		if _, err := c.emitChecked(code.OpConstant, c.addConstant(str)); err != nil {
+			return err
+		}
 	case *ast.ArrayLiteral:
 		for _, el := range node.Elements {
 			if err := c.Compile(el); err != nil {
 				return err
 			}
 		}
-		c.emit(code.OpArray, len(node.Elements))
-	case *ast.HashLiteral:
-		keys := []ast.Expression{}
-		for k := range node.Pairs {
-			keys = append(keys, k)
+		if _, err := c.emitChecked(code.OpArray, len(node.Elements)); err != nil {
+			return err
 		}
-
-		sort.Slice(keys, func(i int, j int) bool {
-			return keys[i].String() < keys[j].String()
-		})
-
-		for _, k := range keys {
+	case *ast.HashLiteral:
+		// Keys are compiled in source order, which is the order the hash
+		// keeps them in.
+		for _, k := range node.OrderedKeys() {
 			if err := c.Compile(k); err != nil {
 				return err
 			}
@@ -224,7 +229,9 @@ func (c *Compiler) Compile(node ast.Node) error {
 			}
 		}
 
-		c.emit(code.OpHash, len(node.Pairs)*2)
+		if _, err := c.emitChecked(code.OpHash, len(node.Pairs)*2); err != nil {
+			return err
+		}
 	case *ast.IndexExpression:
 		if err := c.Compile(node.Left); err != nil {
 			return err
@@ -235,6 +242,23 @@ func (c *Compiler) Compile(node ast.Node) error {
 		}
 
 		c.emit(code.OpIndex)
+	case *ast.SliceExpression:
+		if err := c.Compile(node.Left); err != nil {
+			return err
+		}
+
+		// A bound that is left out is pushed as null.
+		for _, bound := range []ast.Expression{node.Start, node.End} {
+			if bound == nil {
+				c.emit(code.OpNull)
+				continue
+			}
+			if err := c.Compile(bound); err != nil {
+				return err
+			}
+		}
+
+		c.emit(code.OpSlice)
 	case *ast.FnLiteral:
 		c.enterScope()
 
@@ -263,13 +287,17 @@ func (c *Compiler) Compile(node ast.Node) error {
 		instructions := c.leaveScope()
 
 		for _, s := range freeSymbols {
-			c.loadSymbol(s)
+			if err := c.loadSymbol(s); err != nil {
+				return err
+			}
 		}
 
 		compiledFn := &representation.CompiledFunction{Instructions: instructions, NumLocals: numLocals, NumParameters: len(node.Parameters)}
 
 		fnIndex := c.addConstant(compiledFn)
-		c.emit(code.OpClosure, fnIndex, len(freeSymbols))
+		if _, err := c.emitChecked(code.OpClosure, fnIndex, len(freeSymbols)); err != nil {
+			return err
+		}
 	case *ast.ReturnStatement:
 		if err := c.Compile(node.ReturnValue); err != nil {
 			return err
@@ -286,7 +314,9 @@ func (c *Compiler) Compile(node ast.Node) error {
 			}
 		}
 
-		c.emit(code.OpCall, len(node.Arguments))
+		if _, err := c.emitChecked(code.OpCall, len(node.Arguments)); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -311,6 +341,16 @@ func (c *Compiler) emit(op code.Opcode, operands ...int) int {
 	return pos
 }
 
+// emitChecked emits op, switching to its wide variant when an operand does not
+// fit, and reports an error instead of emitting truncated bytecode.
+func (c *Compiler) emitChecked(op code.Opcode, operands ...int) (int, error) {
+	op, err := code.Widen(op, operands...)
+	if err != nil {
+		return 0, err
+	}
+	return c.emit(op, operands...), nil
+}
+
 func (c *Compiler) setLastInstruction(op code.Opcode, pos int) {
 	previous := c.scopes[c.scopeIndex].lastInstruction
 	last := EmmitedInstruction{Opcode: op, Position: pos}
@@ -390,17 +430,19 @@ func (c *Compiler) replaceLastPopWithReturn() {
 	c.scopes[c.scopeIndex].lastInstruction.Opcode = code.OpReturnValue
 }
 
-func (c *Compiler) loadSymbol(s symboltable.Symbol) {
+func (c *Compiler) loadSymbol(s symboltable.Symbol) error {
+	var err error
 	switch s.Scope {
 	case symboltable.GlobalScope:
-		c.emit(code.OpGetGlobal, s.Index)
+		_, err = c.emitChecked(code.OpGetGlobal, s.Index)
 	case symboltable.LocalScope:
-		c.emit(code.OpGetLocal, s.Index)
+		_, err = c.emitChecked(code.OpGetLocal, s.Index)
 	case symboltable.BuiltinScope:
-		c.emit(code.OpGetBuiltin, s.Index)
+		_, err = c.emitChecked(code.OpGetBuiltin, s.Index)
 	case symboltable.FreeScope:
-		c.emit(code.OpGetFree, s.Index)
+		_, err = c.emitChecked(code.OpGetFree, s.Index)
 	case symboltable.FnScope:
 		c.emit(code.OpCurrentClosure)
 	}
+	return err
 }
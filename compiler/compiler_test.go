@@ -2,6 +2,7 @@ package compiler
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/mislavperi/adl-lang/ast"
@@ -571,6 +572,19 @@ func TestIndexExpressions(t *testing.T) {
 				code.Make(code.OpPop),
 			},
 		},
+		{
+			input:             "[1, 2][1:]",
+			expectedConstants: []interface{}{1, 2, 1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpArray, 2),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpNull),
+				code.Make(code.OpSlice),
+				code.Make(code.OpPop),
+			},
+		},
 	}
 
 	runCompilerTests(t, tests)
@@ -997,6 +1011,45 @@ func TestRecursiveFunctions(t *testing.T) {
 	runCompilerTests(t, tests)
 }
 
+func TestWideOperands(t *testing.T) {
+	params := make([]string, 300)
+	for i := range params {
+		// Identifiers cannot contain digits, so the names are spelled in letters.
+		params[i] = "p" + string(rune('a'+i/26)) + string(rune('a'+i%26))
+	}
+	input := fmt.Sprintf("fn(%s) { %s }", strings.Join(params, ", "), params[299])
+
+	compiler := New()
+	if err := compiler.Compile(parse(input)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	constants := compiler.Bytecode().Constants
+	fn, ok := constants[len(constants)-1].(*representation.CompiledFunction)
+	if !ok {
+		t.Fatalf("constant is not a function: %T", constants[len(constants)-1])
+	}
+
+	err := testInstructions([]code.Instructions{
+		code.Make(code.OpGetLocalWide, 299),
+		code.Make(code.OpReturnValue),
+	}, fn.Instructions)
+	if err != nil {
+		t.Fatalf("testInstructions failed: %s", err)
+	}
+
+	elements := make([]string, 70000)
+	for i := range elements {
+		elements[i] = "1"
+	}
+	input = fmt.Sprintf("[%s]", strings.Join(elements, ", "))
+
+	compiler = New()
+	if err := compiler.Compile(parse(input)); err == nil {
+		t.Fatalf("expected error compiling array with %d elements", len(elements))
+	}
+}
+
 func parse(input string) *ast.Program {
 	l := lexer.New(input)
 	p := parser.New(l)
@@ -0,0 +1,19 @@
+package compiler
+
+import (
+	"fmt"
+
+	"github.com/mislavperi/adl-lang/token"
+)
+
+// Error is a compile error located at the token of the node that caused it.
+type Error struct {
+	Message string
+	Token   token.Token
+}
+
+func (e *Error) Error() string { return e.Message }
+
+func errorAt(tok token.Token, format string, args ...interface{}) *Error {
+	return &Error{Message: fmt.Sprintf(format, args...), Token: tok}
+}
@@ -0,0 +1,192 @@
+// Package engine runs parsed ADL programs on either the bytecode VM or the
+// tree-walking evaluator behind a single interface.
+package engine
+
+import (
+	"fmt"
+
+	"github.com/mislavperi/adl-lang/ast"
+	"github.com/mislavperi/adl-lang/compiler"
+	"github.com/mislavperi/adl-lang/eval"
+	"github.com/mislavperi/adl-lang/representation"
+	symboltable "github.com/mislavperi/adl-lang/symbol_table"
+	"github.com/mislavperi/adl-lang/vm"
+)
+
+const (
+	VM   = "vm"
+	Eval = "eval"
+)
+
+// Names lists the engines accepted by New.
+var Names = []string{VM, Eval}
+
+// Engine executes programs, keeping global state between calls to Run.
+type Engine interface {
+	// Run executes program and returns the value of its last expression
+	// statement, or nil if there is none.
+	Run(program *ast.Program) (representation.Representation, error)
+}
+
+// New returns the engine registered under name, running builtins against
+// host. A nil host gives scripts no arguments and no environment.
+func New(name string, host *representation.Host) (Engine, error) {
+	switch name {
+	case VM:
+		return NewVM(host), nil
+	case Eval:
+		return NewEval(host), nil
+	default:
+		return nil, fmt.Errorf("unknown engine %q, expected one of %v", name, Names)
+	}
+}
+
+// VMEngine compiles programs to bytecode and runs them on the VM.
+type VMEngine struct {
+	constants   []representation.Representation
+	globals     []representation.Representation
+	symbolTable *symboltable.SymbolTable
+	host        *representation.Host
+}
+
+func NewVM(host *representation.Host) *VMEngine {
+	if host == nil {
+		host = representation.NewHost()
+	}
+
+	symbolTable := symboltable.NewSymbolTable()
+	for index, builtin := range representation.Builtins {
+		symbolTable.DefineBuiltin(index, builtin.Name)
+	}
+
+	return &VMEngine{
+		constants:   []representation.Representation{},
+		globals:     make([]representation.Representation, vm.GlobalsSize),
+		symbolTable: symbolTable,
+		host:        host,
+	}
+}
+
+// NewVMWithState returns an engine that continues from previously saved
+// state, such as a REPL session restored from disk.
+func NewVMWithState(host *representation.Host, constants []representation.Representation,
+	globals []representation.Representation, symbolTable *symboltable.SymbolTable) *VMEngine {
+	if host == nil {
+		host = representation.NewHost()
+	}
+
+	return &VMEngine{
+		constants:   constants,
+		globals:     globals,
+		symbolTable: symbolTable,
+		host:        host,
+	}
+}
+
+func (e *VMEngine) Run(program *ast.Program) (representation.Representation, error) {
+	comp := compiler.NewWithState(e.symbolTable, e.constants)
+	if err := comp.Compile(program); err != nil {
+		return nil, fmt.Errorf("compilation failed: %w", err)
+	}
+
+	code := comp.Bytecode()
+	e.constants = code.Constants
+
+	result, err := e.run(code)
+	if err != nil || !producesValue(program) {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Compile compiles program against a copy of the engine's state, leaving the
+// engine unchanged. It is used to inspect the bytecode a program would run.
+func (e *VMEngine) Compile(program *ast.Program) (*compiler.Bytecode, error) {
+	comp := compiler.NewWithState(e.symbolTable.Clone(), e.constants)
+	if err := comp.Compile(program); err != nil {
+		return nil, err
+	}
+	return comp.Bytecode(), nil
+}
+
+// SymbolTable returns the global symbol table shared by every Run.
+func (e *VMEngine) SymbolTable() *symboltable.SymbolTable {
+	return e.symbolTable
+}
+
+// Constants returns the constants compiled so far.
+func (e *VMEngine) Constants() []representation.Representation {
+	return e.constants
+}
+
+// Globals returns the global store shared by every Run.
+func (e *VMEngine) Globals() []representation.Representation {
+	return e.globals
+}
+
+// RunBytecode runs already compiled bytecode, such as a file produced by
+// `adl build`, and returns the last value popped off the stack. Bytecode that
+// was not made by the compiler can still misuse the stack, which is reported
+// as an error rather than crashing the VM.
+func (e *VMEngine) RunBytecode(code *compiler.Bytecode) (result representation.Representation, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, fmt.Errorf("invalid bytecode: %v", r)
+		}
+	}()
+	return e.run(code)
+}
+
+func (e *VMEngine) run(code *compiler.Bytecode) (representation.Representation, error) {
+	machine := vm.NewWithGlobalStore(code, e.globals)
+	machine.SetHost(e.host)
+	if err := machine.Run(); err != nil {
+		return nil, err
+	}
+	return machine.LastPoppedStackElem(), nil
+}
+
+// EvalEngine walks the AST directly with the evaluator.
+type EvalEngine struct {
+	env *representation.Environment
+}
+
+func NewEval(host *representation.Host) *EvalEngine {
+	if host == nil {
+		host = representation.NewHost()
+	}
+	host.Call = func(fn representation.Representation, args ...representation.Representation) representation.Representation {
+		return eval.Apply(host, fn, args...)
+	}
+	return &EvalEngine{env: representation.NewEnvironmentWithHost(host)}
+}
+
+func (e *EvalEngine) Run(program *ast.Program) (representation.Representation, error) {
+	result := eval.Evaluate(program, e.env)
+	switch result := result.(type) {
+	case *representation.Error:
+		return nil, fmt.Errorf("%s", result.Message)
+	case error:
+		return nil, result
+	}
+
+	if !producesValue(program) {
+		return nil, nil
+	}
+	return result, nil
+}
+
+// producesValue reports whether the program ends in a statement that yields a
+// value, as opposed to a let binding.
+func producesValue(program *ast.Program) bool {
+	if len(program.Statements) == 0 {
+		return false
+	}
+
+	switch program.Statements[len(program.Statements)-1].(type) {
+	case *ast.ExpressionStatement, *ast.ReturnStatement:
+		return true
+	default:
+		return false
+	}
+}
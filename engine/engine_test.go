@@ -0,0 +1,213 @@
+package engine
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"testing"
+
+	"github.com/mislavperi/adl-lang/code"
+	"github.com/mislavperi/adl-lang/compiler"
+	"github.com/mislavperi/adl-lang/lexer"
+	adlparser "github.com/mislavperi/adl-lang/parser"
+	"github.com/mislavperi/adl-lang/representation"
+)
+
+// differentialSources are the Go test files whose ADL programs are replayed
+// through both engines.
+var differentialSources = []string{
+	"../vm/vm_test.go",
+	"../eval/eval_test.go",
+}
+
+func TestEnginesAgree(t *testing.T) {
+	for _, source := range differentialSources {
+		programs, err := collectPrograms(source)
+		if err != nil {
+			t.Fatalf("collecting programs from %s: %s", source, err)
+		}
+		if len(programs) == 0 {
+			t.Fatalf("no programs found in %s", source)
+		}
+
+		for _, input := range programs {
+			p := adlparser.New(lexer.New(input))
+			program := p.ParseProgram()
+			if len(p.Errors()) != 0 {
+				continue
+			}
+
+			vmResult, vmErr := NewVM(nil).Run(program)
+			evalResult, evalErr := NewEval(nil).Run(program)
+
+			if !sameOutcome(vmResult, vmErr, evalResult, evalErr) {
+				t.Errorf("engines disagree on %q:\nvm:   %s\neval: %s",
+					input, describe(vmResult, vmErr), describe(evalResult, evalErr))
+			}
+		}
+	}
+}
+
+func TestErrorsStopBothEngines(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let x = int("abc"); out("continuing"); 1`, "`int`: cannot parse \"abc\" as an integer"},
+		{`map([1], fn(x) { len(x) })`, "argument to `len` not supported, got INTEGER"},
+		{`let f = fn() { f() }; f()`, "stack overflow"},
+	}
+
+	for _, tt := range tests {
+		program := adlparser.New(lexer.New(tt.input)).ParseProgram()
+		for _, name := range Names {
+			var out bytes.Buffer
+			engine, _ := New(name, &representation.Host{Stdout: &out})
+			if _, err := engine.Run(program); err == nil || err.Error() != tt.expected {
+				t.Errorf("%s: wrong error for %q. want=%q, got=%v", name, tt.input, tt.expected, err)
+			}
+			if out.Len() != 0 {
+				t.Errorf("%s: %q kept running after the error, printing %q", name, tt.input, out.String())
+			}
+		}
+	}
+}
+
+func TestRunBytecodeReportsBadStack(t *testing.T) {
+	bytecode := &compiler.Bytecode{Instructions: code.Make(code.OpPop), Constants: nil}
+	if _, err := NewVM(nil).RunBytecode(bytecode); err == nil {
+		t.Errorf("expected error popping an empty stack")
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, name := range Names {
+		if _, err := New(name, nil); err != nil {
+			t.Errorf("New(%q) failed: %s", name, err)
+		}
+	}
+
+	if _, err := New("jit", nil); err == nil {
+		t.Errorf("expected error for unknown engine")
+	}
+}
+
+// collectPrograms extracts the ADL inputs from a Go test file: the first string
+// literal of every test-case literal and every `input := "..."` assignment.
+func collectPrograms(filename string) ([]string, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), filename, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	programs := []string{}
+	add := func(expr ast.Expr) {
+		lit, ok := expr.(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return
+		}
+		if value, err := strconv.Unquote(lit.Value); err == nil {
+			programs = append(programs, value)
+		}
+	}
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.CompositeLit:
+			for _, elt := range n.Elts {
+				testCase, ok := elt.(*ast.CompositeLit)
+				if !ok || len(testCase.Elts) == 0 {
+					continue
+				}
+				first := testCase.Elts[0]
+				if kv, ok := first.(*ast.KeyValueExpr); ok {
+					first = kv.Value
+				}
+				add(first)
+			}
+		case *ast.AssignStmt:
+			if len(n.Lhs) == 1 && len(n.Rhs) == 1 {
+				if ident, ok := n.Lhs[0].(*ast.Ident); ok && ident.Name == "input" {
+					add(n.Rhs[0])
+				}
+			}
+		}
+		return true
+	})
+
+	return programs, nil
+}
+
+// sameOutcome compares two engine results. Errors that stopped a program are
+// only compared by their presence, since the engines phrase runtime errors
+// differently, while error values are compared like any other value.
+func sameOutcome(
+	left representation.Representation, leftErr error,
+	right representation.Representation, rightErr error,
+) bool {
+	if leftErr != nil || rightErr != nil {
+		return leftErr != nil && rightErr != nil
+	}
+
+	return sameValue(left, right)
+}
+
+func sameValue(left, right representation.Representation) bool {
+	if left == nil || right == nil {
+		return left == nil && right == nil
+	}
+
+	switch left := left.(type) {
+	case *representation.Integer, *representation.Float, *representation.Boolean, *representation.String, *representation.Null,
+		*representation.Error:
+		return left.Type() == right.Type() && left.Inspect() == right.Inspect()
+	case *representation.Array:
+		other, ok := right.(*representation.Array)
+		if !ok || len(left.Elements) != len(other.Elements) {
+			return false
+		}
+		for i := range left.Elements {
+			if !sameValue(left.Elements[i], other.Elements[i]) {
+				return false
+			}
+		}
+		return true
+	case *representation.Hash:
+		other, ok := right.(*representation.Hash)
+		if !ok || len(left.Pairs) != len(other.Pairs) {
+			return false
+		}
+		for key, pair := range left.Pairs {
+			otherPair, ok := other.Pairs[key]
+			if !ok || !sameValue(pair.Value, otherPair.Value) {
+				return false
+			}
+		}
+		return true
+	default:
+		// Functions are represented differently by each engine, so any two
+		// callables are considered equal.
+		return isCallable(left) && isCallable(right)
+	}
+}
+
+func isCallable(obj representation.Representation) bool {
+	switch obj.(type) {
+	case *representation.Closure, *representation.Function, *representation.Builtin:
+		return true
+	default:
+		return false
+	}
+}
+
+func describe(obj representation.Representation, err error) string {
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	if obj == nil {
+		return "<nil>"
+	}
+	return obj.Inspect()
+}
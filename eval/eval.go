@@ -8,13 +8,15 @@ import (
 )
 
 var (
-	NULL  = &representation.Null{}
-	TRUE  = &representation.Boolean{Value: true}
-	FALSE = &representation.Boolean{Value: false}
+	NULL  = representation.NULL
+	TRUE  = representation.TRUE
+	FALSE = representation.FALSE
 )
 
+// isError reports whether obj stops evaluation, either as a runtime error or
+// as a value that implements error, such as a request to exit.
 func isError(obj representation.Representation) bool {
-	return obj != nil && obj.Type() == representation.ERROR_REPR
+	return obj != nil && representation.Stops(obj)
 }
 
 func Evaluate(node ast.Node, env *representation.Environment) representation.Representation {
@@ -27,7 +29,7 @@ func Evaluate(node ast.Node, env *representation.Environment) representation.Rep
 				if returnValue, ok := result.(*representation.ReturnValue); ok {
 					return returnValue.Value
 				}
-				if result.Type() == representation.ERROR_REPR {
+				if isError(result) {
 					return result
 				}
 			}
@@ -40,6 +42,9 @@ func Evaluate(node ast.Node, env *representation.Environment) representation.Rep
 	case *ast.IntegerLiteral:
 		return &representation.Integer{Value: node.Value}
 
+	case *ast.FloatLiteral:
+		return &representation.Float{Value: node.Value}
+
 	case *ast.Boolean:
 		if node.Value {
 			return TRUE
@@ -65,12 +70,11 @@ func Evaluate(node ast.Node, env *representation.Environment) representation.Rep
 				return FALSE
 			}
 		case "-":
-			if right.Type() != representation.INTEGER_REPR {
-				return newError("unknown operator: -%s", right.Type())
+			result, err := representation.Negate(right)
+			if err != nil {
+				return newError("%s", err)
 			}
-
-			value := right.(*representation.Integer).Value
-			return &representation.Integer{Value: -value}
+			return result
 		default:
 			return newError("unknown operator: %s%s", node.Operator, right.Type())
 		}
@@ -86,49 +90,55 @@ func Evaluate(node ast.Node, env *representation.Environment) representation.Rep
 			return right
 		}
 
-		if left.Type() != right.Type() {
+		// Values of any two types can be compared for equality.
+		numbers := representation.IsNumber(left) && representation.IsNumber(right)
+		equality := node.Operator == "==" || node.Operator == "!="
+		if left.Type() != right.Type() && !numbers && !equality {
 			return newError("type mismatch: %s %s %s", left.Type(), node.Operator, right.Type())
 		}
 
 		switch {
-		case left.Type() == representation.INTEGER_REPR && right.Type() == representation.INTEGER_REPR:
-			leftVal := left.(*representation.Integer).Value
-			rightVal := right.(*representation.Integer).Value
-
+		case numbers:
 			switch node.Operator {
-			case "+":
-				return &representation.Integer{Value: leftVal + rightVal}
-			case "-":
-				return &representation.Integer{Value: leftVal - rightVal}
-			case "*":
-				return &representation.Integer{Value: leftVal * rightVal}
-			case "/":
-				return &representation.Integer{Value: leftVal / rightVal}
+			case "+", "-", "*", "/":
+				result, err := representation.Arithmetic(node.Operator, left, right)
+				if err != nil {
+					return newError("%s", err)
+				}
+				return result
 			case "<":
-				return booleanToBooleanRepresentation(leftVal < rightVal)
+				order, _ := representation.Compare(left, right)
+				return booleanToBooleanRepresentation(order < 0)
 			case ">":
-				return booleanToBooleanRepresentation(leftVal > rightVal)
+				order, _ := representation.Compare(left, right)
+				return booleanToBooleanRepresentation(order > 0)
 			case "==":
-				return booleanToBooleanRepresentation(leftVal == rightVal)
+				return booleanToBooleanRepresentation(representation.Equal(left, right))
 			case "!=":
-				return booleanToBooleanRepresentation(leftVal != rightVal)
+				return booleanToBooleanRepresentation(!representation.Equal(left, right))
 			default:
 				return newError("unknown operator: %s %s %s", left.Type(), node.Operator, right.Type())
 			}
 
-		case left.Type() == representation.STRING_REPR && right.Type() == representation.STRING_REPR:
-			if node.Operator != "+" {
-				return newError("unknown operator: %s %s %s", left.Type(), node.Operator, right.Type())
-			}
-
+		case left.Type() == representation.STRING_REPR && right.Type() == representation.STRING_REPR && node.Operator == "+":
 			leftVal := left.(*representation.String).Value
 			rightVal := right.(*representation.String).Value
 			return &representation.String{Value: leftVal + rightVal}
 
+		case representation.Ordered(left) && (node.Operator == "<" || node.Operator == ">"):
+			order, err := representation.Compare(left, right)
+			if err != nil {
+				return err
+			}
+			if node.Operator == "<" {
+				return booleanToBooleanRepresentation(order < 0)
+			}
+			return booleanToBooleanRepresentation(order > 0)
+
 		case node.Operator == "==":
-			return booleanToBooleanRepresentation(left == right)
+			return booleanToBooleanRepresentation(representation.Equal(left, right))
 		case node.Operator == "!=":
-			return booleanToBooleanRepresentation(left != right)
+			return booleanToBooleanRepresentation(!representation.Equal(left, right))
 		default:
 			return newError("unknown operator: %s %s %s", left.Type(), node.Operator, right.Type())
 		}
@@ -139,7 +149,7 @@ func Evaluate(node ast.Node, env *representation.Environment) representation.Rep
 		for _, statement := range node.Statements {
 			result = Evaluate(statement, env)
 			if result != nil {
-				if result.Type() == representation.RETURN_VALUE_REPR || result.Type() == representation.ERROR_REPR {
+				if result.Type() == representation.RETURN_VALUE_REPR || isError(result) {
 					return result
 				}
 			}
@@ -181,7 +191,10 @@ func Evaluate(node ast.Node, env *representation.Environment) representation.Rep
 			return val
 		}
 
-		if builtin, ok := builtins[node.Value]; ok {
+		if builtin := representation.GetBuiltinByName(node.Value); builtin != nil {
+			if builtin.Value != nil {
+				return builtin.Value
+			}
 			return builtin
 		}
 
@@ -203,22 +216,7 @@ func Evaluate(node ast.Node, env *representation.Environment) representation.Rep
 			return args[0]
 		}
 
-		switch fn := function.(type) {
-		case *representation.Function:
-			extendedEnv := representation.NewEnclosedEnvironment(fn.Env)
-			for paramIdx, param := range fn.Parameters {
-				extendedEnv.Set(param.Value, args[paramIdx])
-			}
-			evaluated := Evaluate(fn.Body, extendedEnv)
-			if returnValue, ok := evaluated.(*representation.ReturnValue); ok {
-				return returnValue.Value
-			}
-			return evaluated
-		case *representation.Builtin:
-			return fn.Fn(args...)
-		default:
-			return newError("not a function: %s", fn.Type())
-		}
+		return Apply(env.Host(), function, args...)
 
 	case *ast.StringLiteral:
 		return &representation.String{Value: node.Value}
@@ -242,21 +240,19 @@ func Evaluate(node ast.Node, env *representation.Environment) representation.Rep
 
 		switch {
 		case left.Type() == representation.ARRAY_REPR && index.Type() == representation.INTEGER_REPR:
-			arrayRepresentation := left.(*representation.Array)
-			idx := index.(*representation.Integer).Value
-			max := int64(len(arrayRepresentation.Elements) - 1)
-			if idx < 0 || idx > max {
-				return NULL
+			element, err := env.Host().Index(left.(*representation.Array), index.(*representation.Integer).Value)
+			if err != nil {
+				return err
 			}
-			return arrayRepresentation.Elements[idx]
+			return element
 
 		case left.Type() == representation.HASH_REPR:
 			hashRepresentation := left.(*representation.Hash)
-			key, ok := index.(representation.Hashable)
+			key, ok := representation.HashKeyOf(index)
 			if !ok {
 				return newError("unusable as hash key: %s", index.Type())
 			}
-			if pair, ok := hashRepresentation.Pairs[key.HashKey()]; ok {
+			if pair, ok := hashRepresentation.Pairs[key]; ok {
 				return pair.Value
 			}
 			return NULL
@@ -265,35 +261,94 @@ func Evaluate(node ast.Node, env *representation.Environment) representation.Rep
 			return newError("index operator not supported: %s", left.Type())
 		}
 
+	case *ast.SliceExpression:
+		left := Evaluate(node.Left, env)
+		if isError(left) {
+			return left
+		}
+		bounds := []representation.Representation{NULL, NULL}
+		for i, bound := range []ast.Expression{node.Start, node.End} {
+			if bound == nil {
+				continue
+			}
+			if bounds[i] = Evaluate(bound, env); isError(bounds[i]) {
+				return bounds[i]
+			}
+		}
+
+		result, err := env.Host().Slice(left, bounds[0], bounds[1])
+		if err != nil {
+			return err
+		}
+		return result
+
 	case *ast.HashLiteral:
-		pairs := make(map[representation.HashKey]representation.HashPair)
+		hash := representation.NewHash()
 
-		for keyNode, valueNode := range node.Pairs {
+		for _, keyNode := range node.OrderedKeys() {
 			key := Evaluate(keyNode, env)
 			if isError(key) {
 				return key
 			}
 
-			hashKey, ok := key.(representation.Hashable)
+			hashKey, ok := representation.HashKeyOf(key)
 			if !ok {
 				return newError("unusable as a hash key:  %s", key.Type())
 			}
 
-			value := Evaluate(valueNode, env)
+			value := Evaluate(node.Pairs[keyNode], env)
 			if isError(value) {
 				return value
 			}
 
-			hashed := hashKey.HashKey()
-			pairs[hashed] = representation.HashPair{Key: key, Value: value}
+			hash.Set(hashKey, representation.HashPair{Key: key, Value: value})
 		}
 
-		return &representation.Hash{Pairs: pairs}
+		return hash
 	}
 
 	return nil
 }
 
+// Apply calls a function or builtin with args, running builtins against host.
+func Apply(host *representation.Host, function representation.Representation, args ...representation.Representation) representation.Representation {
+	if err := host.Err(); err != nil {
+		return &representation.Halt{Err: err}
+	}
+
+	switch fn := function.(type) {
+	case *representation.Function:
+		if len(args) != len(fn.Parameters) {
+			return newError("wrong number of arguments: want=%d, got=%d", len(fn.Parameters), len(args))
+		}
+
+		if err := host.EnterCall(); err != nil {
+			return err
+		}
+		defer host.LeaveCall()
+
+		extendedEnv := representation.NewEnclosedEnvironment(fn.Env)
+		for paramIdx, param := range fn.Parameters {
+			extendedEnv.Set(param.Value, args[paramIdx])
+		}
+		evaluated := Evaluate(fn.Body, extendedEnv)
+		if returnValue, ok := evaluated.(*representation.ReturnValue); ok {
+			return returnValue.Value
+		}
+		if evaluated == nil {
+			return NULL
+		}
+		return evaluated
+	case *representation.Builtin:
+		if result := fn.Fn(host, args...); result != nil {
+			return result
+		}
+		return NULL
+	default:
+		return newError("not a function: %s", fn.Type())
+	}
+}
+
 func booleanToBooleanRepresentation(input bool) *representation.Boolean {
 	if input {
 		return TRUE
@@ -1,6 +1,8 @@
 package eval
 
 import (
+	"context"
+	"errors"
 	"testing"
 
 	"github.com/mislavperi/adl-lang/lexer"
@@ -35,11 +37,41 @@ func TestEvalIntegerExpression(t *testing.T) {
 	}
 }
 
+func TestEvalFloatExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1.5", "1.5"},
+		{"-2.5 + 1", "-1.5"},
+		{"1 / 4.0", "0.25"},
+		{"2.0 * 3", "6.0"},
+		{"1e21", "1e+21"},
+		{"sqrt(2) * sqrt(2) > 1.9", "true"},
+		{"1 < 1.5", "true"},
+		{"3.0 == 3", "true"},
+		{"floor(-2.5)", "-3"},
+		{"max(1, 2.5, 2)", "2.5"},
+		{"pow(3, 4)", "81"},
+		{"round(PI * 100)", "314"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: want=%s, got=%v", tt.input, tt.expected, evaluated)
+		}
+	}
+}
+
 func testEval(input string) representation.Representation {
 	l := lexer.New(input)
 	p := parser.New(l)
 	program := p.ParseProgram()
 	environment := representation.NewEnvironment()
+	host := environment.Host()
+	host.Call = func(fn representation.Representation, args ...representation.Representation) representation.Representation {
+		return Apply(host, fn, args...)
+	}
 	return Evaluate(program, environment)
 }
 
@@ -81,6 +113,16 @@ func TestEvalBooleanExpression(t *testing.T) {
 		{"(1 < 2) == false", false},
 		{"(1 > 2) == true", false},
 		{"(1 > 2) == false", true},
+		{`"a" + "b" == "ab"`, true},
+		{`[1, [2]] == [1, [2]]`, true},
+		{`{"a": 1, "b": 2} != {"b": 2, "a": 1}`, false},
+		{`[1] == "1"`, false},
+		{`{[1, 2]: true}[[1, 2]]`, true},
+		{`"apple" < "banana"`, true},
+		{`"ab" > "abc"`, false},
+		{`[1, 2] < [1, 3]`, true},
+		{`[1, [2, "b"]] > [1, [2, "a"]]`, true},
+		{`[1] < [1, 0]`, true},
 	}
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
@@ -266,6 +308,46 @@ func TestErrorHandling(t *testing.T) {
 			`{"name": "Random"}[fn(x) { x }];`,
 			"unusable as hash key: FUNCTION",
 		},
+		{
+			"fn(a, b) { a + b }(1)",
+			"wrong number of arguments: want=2, got=1",
+		},
+		{
+			"let f = fn() { f() }; f()",
+			"stack overflow",
+		},
+		{
+			`let x = int("abc"); 1`,
+			"`int`: cannot parse \"abc\" as an integer",
+		},
+		{
+			`{[1, fn() { 1 }]: 1}`,
+			"unusable as a hash key:  ARRAY",
+		},
+		{
+			`[1] < ["a"]`,
+			"cannot compare INTEGER with STRING",
+		},
+		{
+			"true > false",
+			"unknown operator: BOOLEAN > BOOLEAN",
+		},
+		{
+			"1.5 + true",
+			"type mismatch: FLOAT + BOOLEAN",
+		},
+		{
+			"MAX_INT + 1",
+			"integer overflow: 9223372036854775807 + 1",
+		},
+		{
+			"-MIN_INT",
+			"integer overflow: -(-9223372036854775808)",
+		},
+		{
+			"10 / 0",
+			"division by zero",
+		},
 	}
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
@@ -376,8 +458,45 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`len("")`, 0},
 		{`len("four")`, 4},
 		{`len("hello world")`, 11},
+		{`len("héllo")`, 5},
+		{`len(substring("héllo", 1, len("héllo")))`, 4},
 		{`len(1)`, "argument to `len` not supported, got INTEGER"},
 		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
+		{`first([1, 2, 3])`, 1},
+		{`last([1, 2, 3])`, 3},
+		{`len(rest([1, 2, 3]))`, 2},
+		{`len(push([1], 2))`, 2},
+		{`rest(1)`, "argument to `rest` must be an array, got INTEGER"},
+		{`len(split("a b c"))`, 3},
+		{`index_of(join(chars("añb"), "-"), "b")`, 4},
+		{`len(substring(upper("héllo"), 1))`, 4},
+		{`repeat("ab")`, "wrong number of arguments, got=1, want=2"},
+		{`repeat("ab", MAX_INT)`, "`repeat`: result would be longer than 1073741824 bytes"},
+		{`trim(1)`, "argument to `trim` must be a string, got INTEGER"},
+		{`reduce(map([1, 2, 3], fn(x) { x * x }), fn(a, b) { a + b })`, 14},
+		{`len(filter(range(10), fn(x) { x > 6 }))`, 3},
+		{`len(range(9223372036854775806, MAX_INT, 5))`, 1},
+		{`sort([3, 1, 2], fn(a, b) { a > b })[0]`, 3},
+		{`first(reverse(sort([2, 9, 4])))`, 9},
+		{`index_of(flatten([[1], [2, 3]]), 3)`, 2},
+		{`map([1], fn(x) { y })`, "identifier not found: y"},
+		{`filter(1, len)`, "argument 1 to `filter` must be an array, got INTEGER"},
+		{`index_of(keys({"b": 1, "a": 2}), "a")`, 1},
+		{`first(values(merge({"a": 1}, {"a": 5})))`, 5},
+		{`len(delete({"a": 1, "b": 2}, "a"))`, 1},
+		{`len(entries({}))`, 0},
+		{`has_key(1, 1)`, "argument 1 to `has_key` must be a hash, got INTEGER"},
+		{`len(json_stringify([1, {"a": 2}]))`, 11},
+		{`json_parse(json_stringify([7]))[0]`, 7},
+		{`json_parse("{")`, "invalid JSON at offset 1: unexpected end of input"},
+		{`len(find_all("a1b22c333", "[0-9]+"))`, 3},
+		{`len(replace_regex("a1b22", "[0-9]+", fn(m) { m[0] + m[0] }))`, 8},
+		{`match("x", "[")`, `match: invalid pattern "[": missing closing ]`},
+		{`int("12") + int(3.5)`, 15},
+		{`len(type(fn(x) { x }))`, 8},
+		{`int("x")`, "`int`: cannot parse \"x\" as an integer"},
+		{`int(bool("true")) + int(bool("false")) + int(bool(0))`, 2},
+		{`bool("x")`, "`bool`: cannot parse \"x\" as a boolean"},
 	}
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
@@ -399,6 +518,53 @@ func TestBuiltinFunctions(t *testing.T) {
 	}
 }
 
+func TestAssertions(t *testing.T) {
+	tests := []struct {
+		input   string
+		failure string
+	}{
+		{`assert(1 < 2); 1`, ""},
+		{`assert(1 > 2); 1`, "assertion failed"},
+		{`assert(if (false) { 1 }, "no value"); 1`, "no value"},
+		{`assert_eq([1, 2], [1, 2]); 1`, ""},
+		{`assert_eq(1 + 1, 3); 1`, "values are not equal\nwant: 3\n got: 2\n      ^"},
+		{`test("inline", fn() { assert_eq("a", "b", "letters") }); 1`, "letters\nwant: b\n got: a\n      ^"},
+		{`test("nested", fn() { let f = fn() { assert(false) }; f(); 2 }); 1`, "assertion failed"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		failure, ok := evaluated.(*representation.Failure)
+		if tt.failure == "" {
+			if ok {
+				t.Errorf("unexpected failure for %q: %s", tt.input, failure.Message)
+			}
+			continue
+		}
+		if !ok {
+			t.Errorf("representation is not Failure. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if failure.Message != tt.failure {
+			t.Errorf("wrong failure message. want=%q, got=%q", tt.failure, failure.Message)
+		}
+	}
+}
+
+func TestCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	environment := representation.NewEnvironmentWithHost(&representation.Host{Context: ctx})
+	program := parser.New(lexer.New(`let f = fn() { 1 }; f()`)).ParseProgram()
+
+	evaluated := Evaluate(program, environment)
+	err, ok := evaluated.(error)
+	if !ok || !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation, got %T (%+v)", evaluated, evaluated)
+	}
+}
+
 func TestArrayLiterals(t *testing.T) {
 	input := "[1, 2 * 2, 3 + 3]"
 	evaluated := testEval(input)
@@ -457,6 +623,14 @@ func TestArrayIndexExpressions(t *testing.T) {
 		},
 		{
 			"[1, 2, 3][-1]",
+			3,
+		},
+		{
+			"[1, 2, 3][-3]",
+			1,
+		},
+		{
+			"[1, 2, 3][-4]",
 			nil,
 		},
 	}
@@ -471,6 +645,55 @@ func TestArrayIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestSliceExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[1, 2, 3, 4][1:3]", "[2, 3]"},
+		{"[1, 2, 3][:2]", "[1, 2]"},
+		{"[1, 2, 3][1:]", "[2, 3]"},
+		{"[1, 2, 3][:]", "[1, 2, 3]"},
+		{"[1, 2, 3][-2:]", "[2, 3]"},
+		{"[1, 2, 3][:-1]", "[1, 2]"},
+		{"[1, 2, 3][-99:99]", "[1, 2, 3]"},
+		{"[1, 2, 3][2:1]", "[]"},
+		{`"héllo"[1:3]`, "él"},
+		{`"hello"[-3:]`, "llo"},
+		{"[1][true:]", "ERROR: slice index must be an integer, got BOOLEAN"},
+		{"{}[1:]", "ERROR: slice operator not supported: HASH"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: wrong result. want=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestStrictIndexing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[1, 2, 3][-1]", "3"},
+		{"[1, 2, 3][1:]", "[2, 3]"},
+		{"[1, 2, 3][3]", "ERROR: index out of range: 3 with length 3"},
+		{"[1, 2, 3][-4]", "ERROR: index out of range: -4 with length 3"},
+		{"[1, 2, 3][1:4]", "ERROR: slice bounds out of range: [1:4] with length 3"},
+		{`"abc"[2:1]`, "ERROR: slice bounds out of range: [2:1] with length 3"},
+	}
+
+	for _, tt := range tests {
+		environment := representation.NewEnvironmentWithHost(&representation.Host{Strict: true})
+		evaluated := Evaluate(parser.New(lexer.New(tt.input)).ParseProgram(), environment)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: wrong result. want=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
 func TestHashIndexExpressions(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -0,0 +1,445 @@
+// Package format prints ADL programs in their canonical layout.
+package format
+
+import (
+	"strings"
+
+	"github.com/mislavperi/adl-lang/ast"
+	"github.com/mislavperi/adl-lang/lexer"
+	"github.com/mislavperi/adl-lang/parser"
+	"github.com/mislavperi/adl-lang/token"
+)
+
+// Indent is the indentation of one nesting level.
+const Indent = "    "
+
+// MaxWidth is the line length beyond which argument lists, arrays and hash
+// literals are broken over several lines.
+const MaxWidth = 80
+
+// Error lists the parser errors of a program that cannot be formatted.
+type Error struct {
+	Errors []string
+}
+
+func (e *Error) Error() string { return strings.Join(e.Errors, "\n") }
+
+// Source formats an ADL program, keeping its comments. A program that does
+// not parse is reported with an *Error.
+func Source(src []byte) ([]byte, error) {
+	l := lexer.New(string(src))
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, &Error{Errors: p.Errors()}
+	}
+
+	pr := &printer{
+		lines:    strings.Split(string(src), "\n"),
+		comments: l.Comments(),
+		closers:  matchingBraces(string(src)),
+	}
+
+	end := token.Token{Type: token.EOF, Line: len(pr.lines) + 1}
+	return []byte(pr.statements(program.Statements, end, 0, false)), nil
+}
+
+type printer struct {
+	lines    []string
+	comments []token.Token
+
+	// next is the first comment not yet printed. Comments are printed in
+	// source order as the statements around them are.
+	next int
+
+	// closers maps each opening brace, bracket or parenthesis to the token
+	// that closes it.
+	closers map[token.Token]token.Token
+}
+
+// statements prints a statement list at depth, one statement per line,
+// along with the comments that appear before end. In a block the final
+// expression statement is the block's value and needs no semicolon.
+func (p *printer) statements(statements []ast.Statement, end token.Token, depth int, block bool) string {
+	var out strings.Builder
+	indent := strings.Repeat(Indent, depth)
+
+	for i, statement := range statements {
+		start := startOf(statement)
+		p.printComments(&out, start, indent)
+
+		if out.Len() > 0 && p.blankBefore(start.Line) {
+			out.WriteString("\n")
+		}
+
+		var next ast.Statement
+		if i+1 < len(statements) {
+			next = statements[i+1]
+		}
+
+		out.WriteString(indent)
+		out.WriteString(p.statement(statement, next, len(indent), depth, block))
+		out.WriteString("\n")
+	}
+
+	p.printComments(&out, end, indent)
+	return out.String()
+}
+
+// printComments prints the comments before pos. A comment that followed code
+// on its line stays at the end of the previous line.
+func (p *printer) printComments(out *strings.Builder, pos token.Token, indent string) {
+	for ; p.next < len(p.comments) && isBefore(p.comments[p.next], pos); p.next++ {
+		comment := p.comments[p.next]
+
+		if out.Len() > 0 && p.isTrailing(comment) {
+			text := strings.TrimSuffix(out.String(), "\n")
+			out.Reset()
+			out.WriteString(text + " " + comment.Literal + "\n")
+			continue
+		}
+
+		if out.Len() > 0 && p.blankBefore(comment.Line) {
+			out.WriteString("\n")
+		}
+		out.WriteString(indent + comment.Literal + "\n")
+	}
+}
+
+func (p *printer) statement(statement ast.Statement, next ast.Statement, col int, depth int, block bool) string {
+	switch statement := statement.(type) {
+	case *ast.LetStatement:
+		prefix := "let " + statement.Name.Value + " = "
+		return prefix + p.expression(statement.Value, col+len(prefix), depth) + ";"
+	case *ast.ReturnStatement:
+		return "return " + p.expression(statement.ReturnValue, col+len("return "), depth) + ";"
+	case *ast.ExpressionStatement:
+		text := p.expression(statement.Expression, col, depth)
+		if next == nil && block {
+			return text
+		}
+		if _, ok := statement.Expression.(*ast.IfExpression); ok && next != nil && !continuesExpression(next) {
+			return text
+		}
+		return text + ";"
+	default:
+		return statement.String()
+	}
+}
+
+// continuesExpression reports whether statement starts with a token that the
+// parser would read as continuing the expression before it if there were no
+// semicolon between them.
+func continuesExpression(statement ast.Statement) bool {
+	switch startOf(statement).Type {
+	case token.LPAREN, token.LBRACKET, token.MINUS:
+		return true
+	default:
+		return false
+	}
+}
+
+// expression prints expr starting at column col. Lists that do not fit in
+// MaxWidth are broken with their elements one level deeper than depth.
+func (p *printer) expression(expr ast.Expression, col int, depth int) string {
+	switch expr := expr.(type) {
+	case *ast.Identifier:
+		return expr.Value
+	case *ast.IntegerLiteral:
+		return expr.Token.Literal
+	case *ast.FloatLiteral:
+		return expr.Token.Literal
+	case *ast.StringLiteral:
+		return `"` + expr.Token.Literal + `"`
+	case *ast.Boolean:
+		return expr.Token.Literal
+	case *ast.PrefixExpression:
+		return expr.Operator + p.operand(expr.Right, precedencePrefix, false, col+len(expr.Operator), depth)
+	case *ast.InfixExpression:
+		precedence := precedences[expr.Operator]
+		left := p.operand(expr.Left, precedence, false, col, depth)
+		middle := " " + expr.Operator + " "
+		right := p.operand(expr.Right, precedence, true, advance(col, left+middle), depth)
+		return left + middle + right
+	case *ast.CallExpression:
+		callee := p.operand(expr.Function, precedenceCall, false, col, depth)
+		return callee + p.list(expr.Token, expr.Arguments, advance(col, callee), depth)
+	case *ast.IndexExpression:
+		left := p.operand(expr.Left, precedenceIndex, false, col, depth)
+		return left + "[" + p.expression(expr.Index, advance(col, left+"["), depth) + "]"
+	case *ast.SliceExpression:
+		out := p.operand(expr.Left, precedenceIndex, false, col, depth) + "["
+		if expr.Start != nil {
+			out += p.expression(expr.Start, advance(col, out), depth)
+		}
+		out += ":"
+		if expr.End != nil {
+			out += p.expression(expr.End, advance(col, out), depth)
+		}
+		return out + "]"
+	case *ast.ArrayLiteral:
+		return p.list(expr.Token, expr.Elements, col, depth)
+	case *ast.HashLiteral:
+		return p.hash(expr, col, depth)
+	case *ast.IfExpression:
+		head := "if (" + p.expression(expr.Condition, col+len("if ("), depth) + ") "
+		saved := p.next
+		consequence := p.block(expr.Consequence, advance(col, head), depth, false)
+		if expr.Alternative == nil {
+			return head + consequence
+		}
+
+		// Both branches are laid out alike: if either needs several lines,
+		// so does the other.
+		broken := strings.Contains(consequence, "\n")
+		alternative := p.block(expr.Alternative, advance(col, head+consequence+" else "), depth, broken)
+		if !broken && strings.Contains(alternative, "\n") {
+			p.next = saved
+			consequence = p.block(expr.Consequence, advance(col, head), depth, true)
+			alternative = p.block(expr.Alternative, 0, depth, true)
+		}
+		return head + consequence + " else " + alternative
+	case *ast.FnLiteral:
+		params := make([]string, len(expr.Parameters))
+		for i, param := range expr.Parameters {
+			params[i] = param.Value
+		}
+		head := "fn(" + strings.Join(params, ", ") + ") "
+		return head + p.block(expr.Body, advance(col, head), depth, false)
+	default:
+		return expr.String()
+	}
+}
+
+// Precedences mirror the parser's, from loosest to tightest binding.
+const (
+	_ int = iota
+	precedenceLowest
+	precedenceEquals
+	precedenceCompare
+	precedenceSum
+	precedenceProduct
+	precedencePrefix
+	precedenceCall
+	precedenceIndex
+)
+
+var precedences = map[string]int{
+	"==": precedenceEquals,
+	"!=": precedenceEquals,
+	"<":  precedenceCompare,
+	">":  precedenceCompare,
+	"+":  precedenceSum,
+	"-":  precedenceSum,
+	"*":  precedenceProduct,
+	"/":  precedenceProduct,
+}
+
+// operand prints an operand of an operator with the given precedence, adding
+// the parentheses the source needed. Operators are left associative, so a
+// right operand of equal precedence needs them too.
+func (p *printer) operand(expr ast.Expression, precedence int, right bool, col int, depth int) string {
+	own := precedenceOf(expr)
+	if own < precedence || (right && own == precedence) {
+		return "(" + p.expression(expr, col+1, depth) + ")"
+	}
+	return p.expression(expr, col, depth)
+}
+
+func precedenceOf(expr ast.Expression) int {
+	switch expr := expr.(type) {
+	case *ast.InfixExpression:
+		return precedences[expr.Operator]
+	case *ast.PrefixExpression:
+		return precedencePrefix
+	case *ast.IfExpression, *ast.FnLiteral:
+		// These end in a block, so anything applied to them reads better
+		// and parses the same inside parentheses.
+		return precedenceLowest
+	default:
+		return precedenceIndex + 1
+	}
+}
+
+// list prints elements between the open token and the one that closes it.
+func (p *printer) list(open token.Token, elements []ast.Expression, col int, depth int) string {
+	element := func(i int, col int, depth int) string {
+		return p.expression(elements[i], col, depth)
+	}
+	start := func(i int) token.Token { return startOf(elements[i]) }
+	return p.items(open, len(elements), element, start, col, depth)
+}
+
+// hash prints a hash literal with its pairs in source order.
+func (p *printer) hash(hash *ast.HashLiteral, col int, depth int) string {
+	keys := hash.OrderedKeys()
+	pair := func(i int, col int, depth int) string {
+		k := p.expression(keys[i], col, depth)
+		return k + ": " + p.expression(hash.Pairs[keys[i]], advance(col, k+": "), depth)
+	}
+	start := func(i int) token.Token { return startOf(keys[i]) }
+	return p.items(hash.Token, len(keys), pair, start, col, depth)
+}
+
+// items prints count items, each printed by item and starting at the token
+// start returns, between open and the token that closes it. They stay on one
+// line if they fit and there are no comments among them, and otherwise go
+// one per line with the comments in between.
+func (p *printer) items(open token.Token, count int, item func(i int, col int, depth int) string, start func(i int) token.Token, col int, depth int) string {
+	closer := p.closers[open]
+	if !p.hasComments(open, closer) {
+		saved := p.next
+		flat := make([]string, count)
+		position := col + len(open.Literal)
+		for i := range flat {
+			flat[i] = item(i, position, depth)
+			position = advance(position, flat[i]+", ")
+		}
+
+		text := open.Literal + strings.Join(flat, ", ") + closer.Literal
+		if fits(col, text) || count == 0 {
+			return text
+		}
+		p.next = saved
+	}
+
+	indent := strings.Repeat(Indent, depth+1)
+	var out strings.Builder
+	out.WriteString(open.Literal + "\n")
+	for i := 0; i < count; i++ {
+		p.printComments(&out, start(i), indent)
+		out.WriteString(indent + item(i, len(indent), depth+1))
+		if i < count-1 {
+			out.WriteString(",")
+		}
+		out.WriteString("\n")
+	}
+	p.printComments(&out, closer, indent)
+	out.WriteString(strings.Repeat(Indent, depth) + closer.Literal)
+	return out.String()
+}
+
+// block prints a block. Unless broken is set, one that only holds an
+// expression stays on the line when it fits and has no comments; others get
+// a line per statement.
+func (p *printer) block(block *ast.BlockStatement, col int, depth int, broken bool) string {
+	closer := p.closers[block.Token]
+	if len(block.Statements) == 0 && !p.hasComments(block.Token, closer) {
+		return "{}"
+	}
+
+	if len(block.Statements) == 1 && !broken && !p.hasComments(block.Token, closer) {
+		if statement, ok := block.Statements[0].(*ast.ExpressionStatement); ok {
+			saved := p.next
+			text := "{ " + p.expression(statement.Expression, col+2, depth) + " }"
+			if fits(col, text) {
+				return text
+			}
+			p.next = saved
+		}
+	}
+
+	return "{\n" + p.statements(block.Statements, closer, depth+1, true) + strings.Repeat(Indent, depth) + "}"
+}
+
+func (p *printer) hasComments(start token.Token, end token.Token) bool {
+	for _, comment := range p.comments[p.next:] {
+		if isBefore(comment, end) && isBefore(start, comment) {
+			return true
+		}
+	}
+	return false
+}
+
+// isTrailing reports whether code precedes comment on its line.
+func (p *printer) isTrailing(comment token.Token) bool {
+	line := p.lines[comment.Line-1]
+	return strings.TrimSpace(line[:comment.Column-1]) != ""
+}
+
+// blankBefore reports whether the source line before line is blank, so that
+// paragraphs of statements stay apart.
+func (p *printer) blankBefore(line int) bool {
+	return line >= 2 && line-2 < len(p.lines) && strings.TrimSpace(p.lines[line-2]) == ""
+}
+
+// fits reports whether text, starting at column col, stays on one line
+// within MaxWidth.
+func fits(col int, text string) bool {
+	return !strings.Contains(text, "\n") && col+len(text) <= MaxWidth
+}
+
+// advance returns the column after printing text from column col.
+func advance(col int, text string) int {
+	if i := strings.LastIndex(text, "\n"); i >= 0 {
+		return len(text) - i - 1
+	}
+	return col + len(text)
+}
+
+func isBefore(a token.Token, b token.Token) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+}
+
+// startOf returns the first token of a statement or expression.
+func startOf(node ast.Node) token.Token {
+	switch node := node.(type) {
+	case *ast.LetStatement:
+		return node.Token
+	case *ast.ReturnStatement:
+		return node.Token
+	case *ast.ExpressionStatement:
+		return node.Token
+	case *ast.InfixExpression:
+		return startOf(node.Left)
+	case *ast.CallExpression:
+		return startOf(node.Function)
+	case *ast.IndexExpression:
+		return startOf(node.Left)
+	case *ast.SliceExpression:
+		return startOf(node.Left)
+	case *ast.Identifier:
+		return node.Token
+	case *ast.IntegerLiteral:
+		return node.Token
+	case *ast.FloatLiteral:
+		return node.Token
+	case *ast.StringLiteral:
+		return node.Token
+	case *ast.Boolean:
+		return node.Token
+	case *ast.PrefixExpression:
+		return node.Token
+	case *ast.IfExpression:
+		return node.Token
+	case *ast.FnLiteral:
+		return node.Token
+	case *ast.ArrayLiteral:
+		return node.Token
+	case *ast.HashLiteral:
+		return node.Token
+	default:
+		return token.Token{}
+	}
+}
+
+// matchingBraces maps each opening brace, bracket or parenthesis to the token
+// that closes it.
+func matchingBraces(source string) map[token.Token]token.Token {
+	closers := map[token.Token]token.Token{}
+	open := []token.Token{}
+
+	l := lexer.New(source)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		switch tok.Type {
+		case token.LBRACE, token.LBRACKET, token.LPAREN:
+			open = append(open, tok)
+		case token.RBRACE, token.RBRACKET, token.RPAREN:
+			if len(open) > 0 {
+				closers[open[len(open)-1]] = tok
+				open = open[:len(open)-1]
+			}
+		}
+	}
+	return closers
+}
@@ -0,0 +1,125 @@
+package format
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mislavperi/adl-lang/lexer"
+	"github.com/mislavperi/adl-lang/parser"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x=1", "let x = 1;\n"},
+		{"let add = fn(a,b){a+b}", "let add = fn(a, b) { a + b };\n"},
+		{"(1 + 2) * 3", "(1 + 2) * 3;\n"},
+		{"1 + (2 * 3)", "1 + 2 * 3;\n"},
+		{"a - (b - c); (a - b) - c", "a - (b - c);\na - b - c;\n"},
+		{"-(a + b); !(-x)", "-(a + b);\n!-x;\n"},
+		{`{"b": 1, "a": 2}`, "{\"b\": 1, \"a\": 2};\n"},
+		{"fn() {}", "fn() {};\n"},
+		{`"a\n\"b\"\\"`, "\"a\\n\\\"b\\\"\\\\\";\n"},
+		{"a[1:n-1]; a[ : 2]; a[1 :]; s[:]", "a[1:n - 1];\na[:2];\na[1:];\ns[:];\n"},
+		{"(a + b)[1:]", "(a + b)[1:];\n"},
+		{"fn(x) { let y = x; y }", "fn(x) {\n    let y = x;\n    y\n};\n"},
+		{"fn(x) { return x; }", "fn(x) {\n    return x;\n};\n"},
+		{
+			"if (x) { 1 } else { let y = 2; y }",
+			"if (x) {\n    1\n} else {\n    let y = 2;\n    y\n};\n",
+		},
+		{"if (x) { 1 } let y = 2;", "if (x) { 1 }\nlet y = 2;\n"},
+		{"if (x) { 1 }; -1", "if (x) { 1 };\n-1;\n"},
+		{
+			"call(argumentNumberOne, argumentNumberTwo, argumentNumberThree, argumentNumberFour)",
+			"call(\n    argumentNumberOne,\n    argumentNumberTwo,\n    argumentNumberThree,\n    argumentNumberFour\n);\n",
+		},
+		{
+			`let h = {"alpha": "first value", "beta": "second value", "gamma": "third value here"};`,
+			"let h = {\n    \"alpha\": \"first value\",\n    \"beta\": \"second value\",\n    \"gamma\": \"third value here\"\n};\n",
+		},
+		{"let a = 1;\n\n\nlet b = 2;", "let a = 1;\n\nlet b = 2;\n"},
+		{"// header\nlet a = 1; // one\n// two\nlet b = 2;\n// end", "// header\nlet a = 1; // one\n// two\nlet b = 2;\n// end\n"},
+		{
+			"let f = fn() {\n// inside\n1 }",
+			"let f = fn() {\n    // inside\n    1\n};\n",
+		},
+		{"let f = fn() {\n1 // one\n}", "let f = fn() {\n    1 // one\n};\n"},
+		{
+			"let h = {\n \"a\": 1, // first\n // before b\n \"b\": 2\n};\nlet x = 1;",
+			"let h = {\n    \"a\": 1, // first\n    // before b\n    \"b\": 2\n};\nlet x = 1;\n",
+		},
+		{"f(1, // one\n2)", "f(\n    1, // one\n    2\n);\n"},
+		{
+			"[ // open\n1,\n\n// two\n2 // last\n]",
+			"[ // open\n    1,\n\n    // two\n    2 // last\n];\n",
+		},
+		{"[[1, // inner\n2], 3]", "[\n    [\n        1, // inner\n        2\n    ],\n    3\n];\n"},
+	}
+
+	for _, tt := range tests {
+		got, err := Source([]byte(tt.input))
+		if err != nil {
+			t.Errorf("%q: unexpected error %s", tt.input, err)
+			continue
+		}
+		if string(got) != tt.expected {
+			t.Errorf("%q: wrong output.\nwant=%q\ngot=%q", tt.input, tt.expected, string(got))
+		}
+	}
+}
+
+func TestSourceIsStable(t *testing.T) {
+	inputs := []string{
+		`let fib = fn(n) { if (n < 2) { return n; } fib(n - 1) + fib(n - 2) }; fib(10)`,
+		`let m = {"one": [1, 2, 3], "two": fn(x) { x * 2 }, true: "yes", 4: {"nested": "hash literal value"}};`,
+		`let apply = fn(f, xs) { map(xs, fn(x) { f(x) + 1 }) }; // trailing
+		// own line
+		apply(fn(x) { x }, [1, 2, 3])[0] - (-1)`,
+		`!(1 < 2) == (3 > 4) != true; "string"; len("abc") * (2 + 3) / 4`,
+		"let h = {\n\"a\": [1, // one\n2], // first\n// before b\n\"b\": f(1, // arg\n2)\n};",
+	}
+
+	for _, input := range inputs {
+		first, err := Source([]byte(input))
+		if err != nil {
+			t.Fatalf("%q: unexpected error %s", input, err)
+		}
+
+		second, err := Source(first)
+		if err != nil {
+			t.Fatalf("formatted %q does not parse: %s\n%s", input, err, first)
+		}
+		if string(first) != string(second) {
+			t.Errorf("formatting is not stable.\nfirst:\n%s\nsecond:\n%s", first, second)
+		}
+
+		if parse(t, input) != parse(t, string(first)) {
+			t.Errorf("formatting changed the program.\nbefore: %s\nafter: %s", parse(t, input), parse(t, string(first)))
+		}
+
+		comments := strings.Count(input, "//")
+		if got := strings.Count(string(first), "//"); got != comments {
+			t.Errorf("wrong number of comments. want=%d, got=%d", comments, got)
+		}
+	}
+}
+
+func TestSourceRejectsInvalidPrograms(t *testing.T) {
+	if _, err := Source([]byte("let = 1;")); err == nil {
+		t.Errorf("expected an error for a program that does not parse")
+	}
+}
+
+func parse(t *testing.T, input string) string {
+	t.Helper()
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("%q: parser errors %v", input, p.Errors())
+	}
+	return program.String()
+}
@@ -1,6 +1,8 @@
 package lexer
 
 import (
+	"strings"
+
 	"github.com/mislavperi/adl-lang/token"
 )
 
@@ -9,19 +11,38 @@ type Lexer struct {
 	position     int
 	readPosition int
 	character    byte
+
+	// line and column locate character in the input.
+	line   int
+	column int
+
+	// comments collects the // comments skipped so far, for tools such as
+	// the formatter that need to keep them.
+	comments []token.Token
+
+	// unterminated is set once a string runs to the end of the input.
+	unterminated bool
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	l := &Lexer{input: input, line: 1}
 	l.readChar()
 	return l
 }
 
 func (l *Lexer) NextToken() token.Token {
-	var tok token.Token
-
 	l.advancePastWhitespace()
 
+	line, column := l.line, l.column
+	tok := l.readToken()
+	tok.Line = line
+	tok.Column = column
+	return tok
+}
+
+func (l *Lexer) readToken() token.Token {
+	var tok token.Token
+
 	switch l.character {
 	case '=':
 		if l.peekChar() == '=' {
@@ -81,8 +102,7 @@ func (l *Lexer) NextToken() token.Token {
 			tok.Type = token.LookupIdentifier(tok.Literal)
 			return tok
 		} else if isDigit(l.character) {
-			tok = token.Token{Type: token.INT, Literal: l.readNumber()}
-			return tok
+			return l.readNumber()
 		} else {
 			tok = token.Token{Type: token.ILLEGAL, Literal: string(l.character)}
 		}
@@ -100,6 +120,14 @@ func (l *Lexer) consumeLiteral() string {
 }
 
 func (l *Lexer) readChar() {
+	if l.character == '\n' {
+		l.line++
+		l.column = 0
+	}
+	if l.readPosition <= len(l.input) {
+		l.column++
+	}
+
 	if l.readPosition >= len(l.input) {
 		l.character = 0
 	} else {
@@ -109,22 +137,60 @@ func (l *Lexer) readChar() {
 	l.readPosition++
 }
 
-func (l *Lexer) readNumber() string {
+// readNumber reads an integer, or a float when the digits are followed by a
+// fraction or an exponent.
+func (l *Lexer) readNumber() token.Token {
 	position := l.position
+	tok := token.Token{Type: token.INT}
+
+	l.readDigits()
+	if l.character == '.' && isDigit(l.peekChar()) {
+		tok.Type = token.FLOAT
+		l.readChar()
+		l.readDigits()
+	}
+	if l.character == 'e' || l.character == 'E' {
+		next := l.readPosition
+		if next < len(l.input) && (l.input[next] == '+' || l.input[next] == '-') {
+			next++
+		}
+		if next < len(l.input) && isDigit(l.input[next]) {
+			tok.Type = token.FLOAT
+			for l.readPosition < next {
+				l.readChar()
+			}
+			l.readChar()
+			l.readDigits()
+		}
+	}
+
+	tok.Literal = l.input[position:l.position]
+	return tok
+}
+
+func (l *Lexer) readDigits() {
 	for isDigit(l.character) {
 		l.readChar()
 	}
-	return l.input[position:l.position]
 }
 
+// readString returns the text between the quotes of a string as it is
+// written, so a backslash does not end the string at the quote after it.
 func (l *Lexer) readString() string {
 	position := l.position + 1
 	for {
 		l.readChar()
+		if l.character == '\\' && l.peekChar() != 0 {
+			l.readChar()
+			continue
+		}
 		if l.character == '"' || l.character == 0 {
 			break
 		}
 	}
+	if l.character == 0 {
+		l.unterminated = true
+	}
 	return l.input[position:l.position]
 }
 
@@ -138,6 +204,10 @@ func (l *Lexer) peekChar() byte {
 
 func (l *Lexer) advancePastWhitespace() {
 	for {
+		if l.character == '/' && l.peekChar() == '/' {
+			l.readComment()
+			continue
+		}
 		if !isWhitespace(l.character) {
 			break
 		}
@@ -145,6 +215,28 @@ func (l *Lexer) advancePastWhitespace() {
 	}
 }
 
+// readComment skips a comment running to the end of the line and records it.
+func (l *Lexer) readComment() {
+	comment := token.Token{Type: token.COMMENT, Line: l.line, Column: l.column}
+	position := l.position
+	for l.character != '\n' && l.character != 0 {
+		l.readChar()
+	}
+	comment.Literal = strings.TrimRight(l.input[position:l.position], " \t\r")
+	l.comments = append(l.comments, comment)
+}
+
+// Comments returns the comments skipped by the tokens read so far.
+func (l *Lexer) Comments() []token.Token {
+	return l.comments
+}
+
+// Unterminated reports whether a string read so far runs to the end of the
+// input without its closing quote.
+func (l *Lexer) Unterminated() bool {
+	return l.unterminated
+}
+
 func isWhitespace(character byte) bool {
 	return character == 32 || character == 9 || character == 10 || character == 13
 }
@@ -1,6 +1,7 @@
 package lexer
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/mislavperi/adl-lang/token"
@@ -141,3 +142,131 @@ if (5 < 10) {
 		}
 	}
 }
+
+func TestTokenPositions(t *testing.T) {
+	input := `let five = 5;
+  "two words" + x
+}`
+
+	tests := []struct {
+		expectedLiteral string
+		expectedLine    int
+		expectedColumn  int
+	}{
+		{"let", 1, 1},
+		{"five", 1, 5},
+		{"=", 1, 10},
+		{"5", 1, 12},
+		{";", 1, 13},
+		{"two words", 2, 3},
+		{"+", 2, 15},
+		{"x", 2, 17},
+		{"}", 3, 1},
+		{"", 3, 2},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok.Line != tt.expectedLine || tok.Column != tt.expectedColumn {
+			t.Fatalf("tests[%d] - position of %q wrong. expected=%d:%d, got=%d:%d",
+				i, tt.expectedLiteral, tt.expectedLine, tt.expectedColumn, tok.Line, tok.Column)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// leading
+let x = 10 / 2; // trailing
+// last`
+
+	l := New(input)
+	literals := []string{}
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		literals = append(literals, tok.Literal)
+	}
+
+	expectedTokens := []string{"let", "x", "=", "10", "/", "2", ";"}
+	if strings.Join(literals, " ") != strings.Join(expectedTokens, " ") {
+		t.Fatalf("wrong tokens. want=%q, got=%q", expectedTokens, literals)
+	}
+
+	expectedComments := []token.Token{
+		{Type: token.COMMENT, Literal: "// leading", Line: 1, Column: 1},
+		{Type: token.COMMENT, Literal: "// trailing", Line: 2, Column: 17},
+		{Type: token.COMMENT, Literal: "// last", Line: 3, Column: 1},
+	}
+	comments := l.Comments()
+	if len(comments) != len(expectedComments) {
+		t.Fatalf("wrong number of comments. want=%d, got=%d", len(expectedComments), len(comments))
+	}
+	for i, want := range expectedComments {
+		if comments[i] != want {
+			t.Errorf("comments[%d] wrong. want=%+v, got=%+v", i, want, comments[i])
+		}
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	input := `1.5 2e3 1.5E-3 10 x.y 3.len 4e`
+
+	expected := []token.Token{
+		{Type: token.FLOAT, Literal: "1.5"},
+		{Type: token.FLOAT, Literal: "2e3"},
+		{Type: token.FLOAT, Literal: "1.5E-3"},
+		{Type: token.INT, Literal: "10"},
+		{Type: token.IDENTIFER, Literal: "x"},
+		{Type: token.ILLEGAL, Literal: "."},
+		{Type: token.IDENTIFER, Literal: "y"},
+		{Type: token.INT, Literal: "3"},
+		{Type: token.ILLEGAL, Literal: "."},
+		{Type: token.IDENTIFER, Literal: "len"},
+		{Type: token.INT, Literal: "4"},
+		{Type: token.IDENTIFER, Literal: "e"},
+		{Type: token.EOF, Literal: ""},
+	}
+
+	l := New(input)
+	for i, want := range expected {
+		tok := l.NextToken()
+		if tok.Type != want.Type || tok.Literal != want.Literal {
+			t.Fatalf("tokens[%d] wrong. want=%s %q, got=%s %q", i, want.Type, want.Literal, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestStringEscapes(t *testing.T) {
+	input := `"say \"hi\"\n" "back\\" x`
+
+	expected := []token.Token{
+		{Type: token.STRING, Literal: `say \"hi\"\n`},
+		{Type: token.STRING, Literal: `back\\`},
+		{Type: token.IDENTIFER, Literal: "x"},
+		{Type: token.EOF, Literal: ""},
+	}
+
+	l := New(input)
+	for i, want := range expected {
+		tok := l.NextToken()
+		if tok.Type != want.Type || tok.Literal != want.Literal {
+			t.Fatalf("tokens[%d] wrong. want=%s %q, got=%s %q", i, want.Type, want.Literal, tok.Type, tok.Literal)
+		}
+	}
+	if l.Unterminated() {
+		t.Errorf("strings reported as unterminated")
+	}
+
+	l = New(`"open \"`)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+	}
+	if !l.Unterminated() {
+		t.Errorf("string without its closing quote not reported as unterminated")
+	}
+}
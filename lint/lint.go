@@ -0,0 +1,301 @@
+// Package lint finds likely mistakes in ADL programs that the compiler
+// accepts but that would fail, or do nothing useful, at runtime.
+package lint
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/mislavperi/adl-lang/analysis"
+	"github.com/mislavperi/adl-lang/ast"
+	symboltable "github.com/mislavperi/adl-lang/symbol_table"
+	"github.com/mislavperi/adl-lang/token"
+)
+
+// Rule is a kind of mistake the linter looks for.
+type Rule struct {
+	Name    string
+	Summary string
+}
+
+// Rules lists every rule. All of them are enabled unless disabled in Config.
+var Rules = []Rule{
+	{"not-callable", "a value that is not a function is called"},
+	{"arity", "a function is called with the wrong number of arguments"},
+	{"unused", "a let binding is never used"},
+	{"shadow", "a binding hides one from an enclosing scope or a builtin"},
+	{"unreachable", "a statement follows a return in the same block"},
+}
+
+// IgnoreDirective starts a comment that suppresses warnings on its own line
+// and the next one, either all of them or only the listed rules:
+//
+//	// adl:ignore unused, shadow
+const IgnoreDirective = "adl:ignore"
+
+// Warning is a problem found by a rule, located at a token.
+type Warning struct {
+	Rule    string
+	Message string
+	Token   token.Token
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%d:%d: %s (%s)", w.Token.Line, w.Token.Column, w.Message, w.Rule)
+}
+
+// Config selects the rules to run.
+type Config struct {
+	Disabled map[string]bool
+}
+
+// ParseRules splits a comma separated list of rule names, rejecting names
+// that are not in Rules.
+func ParseRules(list string) (map[string]bool, error) {
+	rules := map[string]bool{}
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !isRule(name) {
+			return nil, fmt.Errorf("unknown rule %q", name)
+		}
+		rules[name] = true
+	}
+	return rules, nil
+}
+
+func isRule(name string) bool {
+	for _, rule := range Rules {
+		if rule.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Check runs the enabled rules over an analysed program and returns the
+// warnings that are not suppressed, in source order.
+func Check(a *analysis.Analysis, config Config) []Warning {
+	l := &linter{analysis: a}
+	for _, statement := range a.Program.Statements {
+		l.walk(statement)
+	}
+	l.statements(a.Program.Statements)
+	l.definitions()
+
+	ignored := ignoredLines(a.Comments)
+	warnings := []Warning{}
+	for _, warning := range l.warnings {
+		if config.Disabled[warning.Rule] || ignored.suppresses(warning) {
+			continue
+		}
+		warnings = append(warnings, warning)
+	}
+
+	sort.SliceStable(warnings, func(i int, j int) bool {
+		a, b := warnings[i].Token, warnings[j].Token
+		return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+	})
+	return warnings
+}
+
+type linter struct {
+	analysis *analysis.Analysis
+	warnings []Warning
+}
+
+func (l *linter) warn(rule string, tok token.Token, format string, args ...interface{}) {
+	l.warnings = append(l.warnings, Warning{Rule: rule, Message: fmt.Sprintf(format, args...), Token: tok})
+}
+
+func (l *linter) walk(node ast.Node) {
+	if node == nil || reflect.ValueOf(node).IsNil() {
+		return
+	}
+
+	switch node := node.(type) {
+	case *ast.LetStatement:
+		l.walk(node.Value)
+	case *ast.ReturnStatement:
+		l.walk(node.ReturnValue)
+	case *ast.ExpressionStatement:
+		l.walk(node.Expression)
+	case *ast.BlockStatement:
+		l.statements(node.Statements)
+		for _, statement := range node.Statements {
+			l.walk(statement)
+		}
+	case *ast.PrefixExpression:
+		l.walk(node.Right)
+	case *ast.InfixExpression:
+		l.walk(node.Left)
+		l.walk(node.Right)
+	case *ast.IfExpression:
+		l.walk(node.Condition)
+		l.walk(node.Consequence)
+		l.walk(node.Alternative)
+	case *ast.FnLiteral:
+		l.walk(node.Body)
+	case *ast.CallExpression:
+		l.call(node)
+		l.walk(node.Function)
+		for _, arg := range node.Arguments {
+			l.walk(arg)
+		}
+	case *ast.ArrayLiteral:
+		for _, element := range node.Elements {
+			l.walk(element)
+		}
+	case *ast.IndexExpression:
+		l.walk(node.Left)
+		l.walk(node.Index)
+	case *ast.SliceExpression:
+		l.walk(node.Left)
+		l.walk(node.Start)
+		l.walk(node.End)
+	case *ast.HashLiteral:
+		for key, value := range node.Pairs {
+			l.walk(key)
+			l.walk(value)
+		}
+	}
+}
+
+// call checks what is being called: a literal, or a name bound by let to one.
+func (l *linter) call(call *ast.CallExpression) {
+	callee := call.Function
+	name := ""
+
+	if identifier, ok := callee.(*ast.Identifier); ok {
+		definition := l.analysis.Resolve(identifier)
+		if definition == nil || definition.Value == nil {
+			// Parameters and builtins can hold anything.
+			return
+		}
+		callee = definition.Value
+		name = "`" + identifier.Value + "`"
+	}
+
+	switch callee := callee.(type) {
+	case *ast.FnLiteral:
+		if len(call.Arguments) == len(callee.Parameters) {
+			return
+		}
+		if name == "" {
+			name = "the function"
+		}
+		l.warn("arity", call.Token, "%s takes %d arguments, called with %d",
+			name, len(callee.Parameters), len(call.Arguments))
+	case *ast.IntegerLiteral, *ast.FloatLiteral, *ast.StringLiteral, *ast.Boolean, *ast.ArrayLiteral, *ast.HashLiteral:
+		if name == "" {
+			l.warn("not-callable", call.Token, "%s is not a function", describe(callee))
+		} else {
+			l.warn("not-callable", call.Token, "%s is %s, not a function", name, describe(callee))
+		}
+	}
+}
+
+func describe(expr ast.Expression) string {
+	switch expr.(type) {
+	case *ast.IntegerLiteral:
+		return "an integer"
+	case *ast.FloatLiteral:
+		return "a float"
+	case *ast.StringLiteral:
+		return "a string"
+	case *ast.Boolean:
+		return "a boolean"
+	case *ast.ArrayLiteral:
+		return "an array"
+	default:
+		return "a hash"
+	}
+}
+
+// statements reports the first statement after a return in a list.
+func (l *linter) statements(statements []ast.Statement) {
+	for i, statement := range statements {
+		if _, ok := statement.(*ast.ReturnStatement); ok && i+1 < len(statements) {
+			l.warn("unreachable", statementToken(statements[i+1]), "unreachable code after return")
+			return
+		}
+	}
+}
+
+func statementToken(statement ast.Statement) token.Token {
+	switch statement := statement.(type) {
+	case *ast.LetStatement:
+		return statement.Token
+	case *ast.ReturnStatement:
+		return statement.Token
+	case *ast.ExpressionStatement:
+		return statement.Token
+	default:
+		return token.Token{}
+	}
+}
+
+// definitions reports unused let bindings and bindings that shadow others.
+// Names starting with an underscore are meant to be unused.
+func (l *linter) definitions() {
+	for _, definition := range l.analysis.Definitions {
+		if definition.Shadows != nil {
+			hidden := "a builtin"
+			if definition.Shadows.Scope != symboltable.BuiltinScope {
+				hidden = fmt.Sprintf("the binding on line %d", definition.Shadows.Token.Line)
+			}
+			l.warn("shadow", definition.Token, "`%s` shadows %s", definition.Name, hidden)
+		}
+
+		if definition.Function != nil || strings.HasPrefix(definition.Name, "_") {
+			continue
+		}
+		// The identifier that introduces a name is its first reference.
+		if len(l.analysis.ReferencesTo(definition)) == 1 {
+			l.warn("unused", definition.Token, "`%s` is never used", definition.Name)
+		}
+	}
+}
+
+// ignored maps a line to the rules suppressed on it. The empty rule name
+// stands for every rule.
+type ignored map[int]map[string]bool
+
+func ignoredLines(comments []token.Token) ignored {
+	lines := ignored{}
+	for _, comment := range comments {
+		text := strings.TrimSpace(strings.TrimPrefix(comment.Literal, "//"))
+		if !strings.HasPrefix(text, IgnoreDirective) {
+			continue
+		}
+
+		rules := []string{}
+		for _, name := range strings.Split(strings.TrimPrefix(text, IgnoreDirective), ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				rules = append(rules, name)
+			}
+		}
+		if len(rules) == 0 {
+			rules = append(rules, "")
+		}
+
+		for _, line := range []int{comment.Line, comment.Line + 1} {
+			if lines[line] == nil {
+				lines[line] = map[string]bool{}
+			}
+			for _, rule := range rules {
+				lines[line][rule] = true
+			}
+		}
+	}
+	return lines
+}
+
+func (i ignored) suppresses(warning Warning) bool {
+	rules := i[warning.Token.Line]
+	return rules[""] || rules[warning.Rule]
+}
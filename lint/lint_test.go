@@ -0,0 +1,72 @@
+package lint
+
+import (
+	"testing"
+
+	"github.com/mislavperi/adl-lang/analysis"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let x = 1; x", []string{}},
+		{"let x = 1;", []string{"1:5: `x` is never used (unused)"}},
+		{"let _x = 1;", []string{}},
+		{"let f = fn(a, b) { a + b }; f(1)", []string{"1:30: `f` takes 2 arguments, called with 1 (arity)"}},
+		{"fn(a) { a }(1, 2)", []string{"1:12: the function takes 1 arguments, called with 2 (arity)"}},
+		{`let s = "str"; s()`, []string{"1:17: `s` is a string, not a function (not-callable)"}},
+		{"5()", []string{"1:2: an integer is not a function (not-callable)"}},
+		{"let g = fn(h) { h(1, 2) }; g(fn(x) { x })", []string{}},
+		{"let len = 1; len", []string{"1:5: `len` shadows a builtin (shadow)"}},
+		{
+			"let x = 1; let f = fn() { let x = 2; x }; f() + x",
+			[]string{"1:31: `x` shadows the binding on line 1 (shadow)"},
+		},
+		{"let x = 1; let x = 2; x", []string{"1:5: `x` is never used (unused)"}},
+		{
+			"let f = fn() { return 1; 2 }; f()",
+			[]string{"1:26: unreachable code after return (unreachable)"},
+		},
+		{"let x = 1; // adl:ignore\n", []string{}},
+		{"// adl:ignore unused\nlet x = 1;", []string{}},
+		{"// adl:ignore shadow\nlet x = 1;", []string{"2:5: `x` is never used (unused)"}},
+		{"// adl:ignore shadow, unused\nlet len = 1;", []string{}},
+	}
+
+	for _, tt := range tests {
+		warnings := Check(analysis.Analyze(tt.input), Config{})
+
+		got := []string{}
+		for _, warning := range warnings {
+			got = append(got, warning.String())
+		}
+
+		if len(got) != len(tt.expected) {
+			t.Errorf("%q: wrong warnings. want=%q, got=%q", tt.input, tt.expected, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.expected[i] {
+				t.Errorf("%q: wrong warning %d. want=%q, got=%q", tt.input, i, tt.expected[i], got[i])
+			}
+		}
+	}
+}
+
+func TestCheckDisabledRules(t *testing.T) {
+	disabled, err := ParseRules("unused, shadow")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	warnings := Check(analysis.Analyze("let len = 1; 5()"), Config{Disabled: disabled})
+	if len(warnings) != 1 || warnings[0].Rule != "not-callable" {
+		t.Errorf("wrong warnings with unused and shadow disabled: %v", warnings)
+	}
+
+	if _, err := ParseRules("unused,typo"); err == nil {
+		t.Errorf("expected an error for an unknown rule")
+	}
+}
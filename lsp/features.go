@@ -0,0 +1,254 @@
+package lsp
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/mislavperi/adl-lang/analysis"
+	"github.com/mislavperi/adl-lang/ast"
+	"github.com/mislavperi/adl-lang/format"
+	"github.com/mislavperi/adl-lang/representation"
+	symboltable "github.com/mislavperi/adl-lang/symbol_table"
+	"github.com/mislavperi/adl-lang/token"
+)
+
+// document is an open file and its analysis.
+type document struct {
+	text     string
+	lines    []string
+	analysis *analysis.Analysis
+}
+
+func newDocument(text string) *document {
+	return &document{text: text, lines: strings.Split(text, "\n"), analysis: analysis.Analyze(text)}
+}
+
+// position converts a 1-based line and byte column into an LSP position,
+// whose character offsets count UTF-16 code units.
+func (d *document) position(line int, column int) Position {
+	if line < 1 || line > len(d.lines) {
+		return Position{Line: max(line-1, 0)}
+	}
+
+	text := d.lines[line-1]
+	offset := min(max(column-1, 0), len(text))
+	return Position{Line: line - 1, Character: len(utf16.Encode([]rune(text[:offset])))}
+}
+
+// column converts an LSP position back into a 1-based line and byte column.
+func (d *document) column(pos Position) (int, int) {
+	if pos.Line < 0 || pos.Line >= len(d.lines) {
+		return pos.Line + 1, pos.Character + 1
+	}
+
+	text := d.lines[pos.Line]
+	units := 0
+	for offset, r := range text {
+		if units >= pos.Character {
+			return pos.Line + 1, offset + 1
+		}
+		units += len(utf16.Encode([]rune{r}))
+	}
+	return pos.Line + 1, len(text) + 1
+}
+
+// tokenRange covers tok, or the rest of its line when it has no text, such as
+// the end of the file.
+func (d *document) tokenRange(tok token.Token) Range {
+	if tok.Line == 0 {
+		return Range{}
+	}
+
+	length := len(tok.Literal)
+	if tok.Type == token.STRING {
+		length += 2
+	}
+	if length == 0 {
+		length = 1
+	}
+	return Range{Start: d.position(tok.Line, tok.Column), End: d.position(tok.Line, tok.Column+length)}
+}
+
+func (s *Server) lookup(params TextDocumentPositionParams) (*document, analysis.Reference, bool) {
+	doc, ok := s.documents[params.TextDocument.URI]
+	if !ok {
+		return nil, analysis.Reference{}, false
+	}
+
+	line, column := doc.column(params.Position)
+	ref, ok := doc.analysis.ReferenceAt(line, column)
+	return doc, ref, ok
+}
+
+func (s *Server) hover(params TextDocumentPositionParams) *Hover {
+	doc, ref, ok := s.lookup(params)
+	if !ok {
+		return nil
+	}
+
+	return &Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: describe(ref.Definition)},
+		Range:    doc.tokenRange(ref.Token),
+	}
+}
+
+// builtinConstant returns the value of a builtin that is a constant, such
+// as PI, or nil.
+func builtinConstant(definition *analysis.Definition) representation.Representation {
+	if definition.Scope != symboltable.BuiltinScope {
+		return nil
+	}
+	if builtin := representation.GetBuiltinByName(definition.Name); builtin != nil {
+		return builtin.Value
+	}
+	return nil
+}
+
+// describe renders a definition as a code block followed by where it lives.
+func describe(definition *analysis.Definition) string {
+	var signature, kind string
+
+	switch {
+	case builtinConstant(definition) != nil:
+		signature = definition.Name + " = " + builtinConstant(definition).Inspect()
+		kind = "builtin constant"
+	case definition.Scope == symboltable.BuiltinScope:
+		signature = definition.Name
+		kind = "builtin function"
+	case definition.Function != nil:
+		signature = definition.Name
+		kind = fmt.Sprintf("parameter of `%s`", functionSignature("fn", definition.Function))
+	default:
+		signature = "let " + definition.Name
+		if fn, ok := definition.Value.(*ast.FnLiteral); ok {
+			signature = functionSignature(signature+" = fn", fn)
+		} else if definition.Value != nil {
+			signature += " = " + truncate(definition.Value.String(), 60)
+		}
+		kind = strings.ToLower(string(definition.Scope)) + " binding"
+	}
+
+	return fmt.Sprintf("```adl\n%s\n```\n%s", signature, kind)
+}
+
+func functionSignature(prefix string, fn *ast.FnLiteral) string {
+	params := make([]string, len(fn.Parameters))
+	for i, param := range fn.Parameters {
+		params[i] = param.Value
+	}
+	return fmt.Sprintf("%s(%s)", prefix, strings.Join(params, ", "))
+}
+
+func truncate(text string, limit int) string {
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+	return string([]rune(text)[:limit]) + "…"
+}
+
+func (s *Server) definition(params TextDocumentPositionParams) []Location {
+	doc, ref, ok := s.lookup(params)
+	if !ok || ref.Definition.Token.Line == 0 {
+		return []Location{}
+	}
+
+	return []Location{{URI: params.TextDocument.URI, Range: doc.tokenRange(ref.Definition.Token)}}
+}
+
+func (s *Server) references(params ReferenceParams) []Location {
+	doc, ref, ok := s.lookup(params.TextDocumentPositionParams)
+	if !ok {
+		return []Location{}
+	}
+
+	locations := []Location{}
+	for _, other := range doc.analysis.ReferencesTo(ref.Definition) {
+		if other.Token == ref.Definition.Token && !params.Context.IncludeDeclaration {
+			continue
+		}
+		locations = append(locations, Location{URI: params.TextDocument.URI, Range: doc.tokenRange(other.Token)})
+	}
+	return locations
+}
+
+// documentSymbols lists the let bindings, nesting those made inside a
+// function under the binding that holds it.
+func (s *Server) documentSymbols(params DocumentSymbolParams) []DocumentSymbol {
+	doc, ok := s.documents[params.TextDocument.URI]
+	if !ok {
+		return []DocumentSymbol{}
+	}
+	return doc.symbols(doc.analysis.Program.Statements)
+}
+
+func (d *document) symbols(statements []ast.Statement) []DocumentSymbol {
+	symbols := []DocumentSymbol{}
+	for _, statement := range statements {
+		let, ok := statement.(*ast.LetStatement)
+		if !ok || let.Name == nil {
+			continue
+		}
+
+		symbol := DocumentSymbol{
+			Name:           let.Name.Value,
+			Kind:           symbolKindVariable,
+			Range:          d.tokenRange(let.Name.Token),
+			SelectionRange: d.tokenRange(let.Name.Token),
+		}
+		if fn, ok := let.Value.(*ast.FnLiteral); ok {
+			symbol.Kind = symbolKindFunction
+			symbol.Detail = functionSignature("fn", fn)
+			if fn.Body != nil {
+				symbol.Children = d.symbols(fn.Body.Statements)
+			}
+		}
+		symbols = append(symbols, symbol)
+	}
+	return symbols
+}
+
+// formatting replaces the whole document with its formatted text. Documents
+// that do not parse are left alone; their errors are already diagnostics.
+func (s *Server) formatting(params DocumentFormattingParams) []TextEdit {
+	doc, ok := s.documents[params.TextDocument.URI]
+	if !ok {
+		return []TextEdit{}
+	}
+
+	formatted, err := format.Source([]byte(doc.text))
+	if err != nil || string(formatted) == doc.text {
+		return []TextEdit{}
+	}
+
+	last := len(doc.lines)
+	end := doc.position(last, len(doc.lines[last-1])+1)
+	return []TextEdit{{Range: Range{End: end}, NewText: string(formatted)}}
+}
+
+func (s *Server) completion(params TextDocumentPositionParams) []CompletionItem {
+	items := []CompletionItem{}
+	for _, keyword := range token.Keywords() {
+		items = append(items, CompletionItem{Label: keyword, Kind: completionKindKeyword})
+	}
+
+	doc, ok := s.documents[params.TextDocument.URI]
+	if !ok {
+		return items
+	}
+
+	line, column := doc.column(params.Position)
+	for _, definition := range doc.analysis.VisibleAt(line, column) {
+		item := CompletionItem{Label: definition.Name, Kind: completionKindVariable}
+		if _, ok := definition.Value.(*ast.FnLiteral); ok || definition.Scope == symboltable.BuiltinScope {
+			item.Kind = completionKindFunction
+		}
+		if builtinConstant(definition) != nil {
+			item.Kind = completionKindConstant
+		}
+		item.Detail = strings.SplitN(describe(definition), "\n", 3)[1]
+		items = append(items, item)
+	}
+	return items
+}
@@ -0,0 +1,171 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const testSource = `let add = fn(a, b) { a + b };
+let total = add(1, 2);
+let outer = fn(x) {
+  let inner = x * 2;
+  inner + total
+};
+`
+
+func TestServer(t *testing.T) {
+	const uri = "file:///test.adl"
+	position := func(line, character int) map[string]interface{} {
+		return map[string]interface{}{
+			"textDocument": map[string]string{"uri": uri},
+			"position":     map[string]int{"line": line, "character": character},
+		}
+	}
+
+	var in bytes.Buffer
+	send := func(id int, method string, params interface{}) {
+		msg := map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params}
+		if id != 0 {
+			msg["id"] = id
+		}
+		content, _ := json.Marshal(msg)
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(content), content)
+	}
+
+	send(1, "initialize", map[string]interface{}{})
+	send(0, "initialized", map[string]interface{}{})
+	send(0, "textDocument/didOpen", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri, "languageId": "adl", "version": 1, "text": testSource + "missing;"},
+	})
+	send(2, "textDocument/hover", position(0, 5))
+	send(3, "textDocument/definition", position(1, 13))
+	send(4, "textDocument/references", map[string]interface{}{
+		"textDocument": map[string]string{"uri": uri},
+		"position":     map[string]int{"line": 0, "character": 5},
+		"context":      map[string]bool{"includeDeclaration": true},
+	})
+	send(5, "textDocument/documentSymbol", map[string]interface{}{"textDocument": map[string]string{"uri": uri}})
+	send(6, "textDocument/completion", position(4, 2))
+	send(7, "unknown/method", map[string]interface{}{})
+	send(9, "textDocument/formatting", map[string]interface{}{"textDocument": map[string]string{"uri": uri}})
+	send(8, "shutdown", nil)
+	send(0, "exit", nil)
+
+	var out bytes.Buffer
+	if err := NewServer(&in, &out, "test").Serve(); err != nil {
+		t.Fatalf("Serve failed: %s", err)
+	}
+
+	results := map[int]string{}
+	var diagnostics string
+	reader := bufio.NewReader(&out)
+	for {
+		content := readTestMessage(t, reader)
+		if content == nil {
+			break
+		}
+
+		var msg struct {
+			ID     int             `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+			Result json.RawMessage `json:"result"`
+			Error  json.RawMessage `json:"error"`
+		}
+		if err := json.Unmarshal(content, &msg); err != nil {
+			t.Fatalf("invalid response %s: %s", content, err)
+		}
+
+		if msg.Method == "textDocument/publishDiagnostics" {
+			diagnostics = string(msg.Params)
+		} else if msg.Error != nil {
+			results[msg.ID] = "error " + string(msg.Error)
+		} else {
+			results[msg.ID] = string(msg.Result)
+		}
+	}
+
+	expectations := []struct {
+		id       int
+		contains []string
+	}{
+		{1, []string{`"hoverProvider":true`, `"textDocumentSync":1`}},
+		{2, []string{"let add = fn(a, b)", "global binding"}},
+		{3, []string{`"start":{"line":0,"character":4}`}},
+		{4, []string{`"line":0`, `"line":1`}},
+		{5, []string{`"name":"add"`, `"name":"outer"`, `"name":"inner"`}},
+		{6, []string{`"label":"inner"`, `"label":"len"`, `"label":"let"`}},
+		{7, []string{"error", "-32601"}},
+		{8, []string{"null"}},
+		{9, []string{`"newText":"let add = fn(a, b) { a + b };\n`, `"end":{"line":6,"character":8}`}},
+	}
+
+	for _, tt := range expectations {
+		result, ok := results[tt.id]
+		if !ok {
+			t.Errorf("no response to request %d", tt.id)
+			continue
+		}
+		for _, want := range tt.contains {
+			if !strings.Contains(result, want) {
+				t.Errorf("response to request %d does not contain %s:\n%s", tt.id, want, result)
+			}
+		}
+	}
+
+	if !strings.Contains(diagnostics, "undefined variable missing") ||
+		!strings.Contains(diagnostics, `"start":{"line":6,"character":0}`) {
+		t.Errorf("wrong diagnostics: %s", diagnostics)
+	}
+}
+
+func TestServerPositionsCountUTF16(t *testing.T) {
+	doc := newDocument("let s = \"é😀\"; s")
+
+	// The last s starts at byte 18 but UTF-16 offset 15.
+	if line, column := doc.column(Position{Line: 0, Character: 15}); line != 1 || column != 19 {
+		t.Errorf("wrong column. want=1:19, got=%d:%d", line, column)
+	}
+	if pos := doc.position(1, 19); pos.Character != 15 {
+		t.Errorf("wrong character. want=15, got=%d", pos.Character)
+	}
+}
+
+func TestServerRejectsBadContentLength(t *testing.T) {
+	for _, length := range []string{"-5", "99999999999", "five"} {
+		in := strings.NewReader("Content-Length: " + length + "\r\n\r\n")
+		if err := NewServer(in, io.Discard, "test").Serve(); err == nil {
+			t.Errorf("expected error for Content-Length %s", length)
+		}
+	}
+}
+
+func readTestMessage(t *testing.T, reader *bufio.Reader) []byte {
+	t.Helper()
+
+	headers, err := textproto.NewReader(reader).ReadMIMEHeader()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		t.Fatalf("reading headers: %s", err)
+	}
+
+	length, err := strconv.Atoi(headers.Get("Content-Length"))
+	if err != nil {
+		t.Fatalf("invalid Content-Length: %s", err)
+	}
+
+	content := make([]byte, length)
+	if _, err := io.ReadFull(reader, content); err != nil {
+		t.Fatal(err)
+	}
+	return content
+}
@@ -0,0 +1,251 @@
+// Package lsp implements a Language Server Protocol server for ADL, speaking
+// JSON-RPC over a pair of streams such as stdin and stdout.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+
+	"github.com/mislavperi/adl-lang/lint"
+)
+
+// ServerName is reported to clients during initialisation.
+const ServerName = "adl"
+
+// Server answers requests for the documents a client has open.
+type Server struct {
+	in      *bufio.Reader
+	out     io.Writer
+	version string
+
+	documents   map[string]*document
+	initialized bool
+	shutdown    bool
+}
+
+func NewServer(in io.Reader, out io.Writer, version string) *Server {
+	return &Server{
+		in:        bufio.NewReader(in),
+		out:       out,
+		version:   version,
+		documents: map[string]*document{},
+	}
+}
+
+// maxMessageLength bounds the messages the server reads, so that a corrupt
+// header is reported rather than allocated.
+const maxMessageLength = 64 << 20
+
+// ErrNoShutdown is returned by Serve when the client exits without asking
+// the server to shut down first.
+var ErrNoShutdown = errors.New("exit without shutdown")
+
+// Serve handles messages until the client sends exit or closes the stream.
+func (s *Server) Serve() error {
+	for {
+		content, err := s.readMessage()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var msg message
+		if err := json.Unmarshal(content, &msg); err != nil {
+			s.reply(nil, nil, &responseError{Code: codeParseError, Message: err.Error()})
+			continue
+		}
+
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return ErrNoShutdown
+			}
+			return nil
+		}
+
+		result, rpcErr := s.handle(msg)
+		if msg.ID != nil {
+			s.reply(msg.ID, result, rpcErr)
+		}
+	}
+}
+
+func (s *Server) handle(msg message) (interface{}, *responseError) {
+	if !s.initialized && msg.Method != "initialize" {
+		return nil, &responseError{Code: codeServerNotInitialized, Message: "server not initialized"}
+	}
+
+	switch msg.Method {
+	case "initialize":
+		s.initialized = true
+		return s.initialize(), nil
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		return s.decode(msg.Params, &params, func() interface{} {
+			s.update(params.TextDocument.URI, params.TextDocument.Text)
+			return nil
+		})
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		return s.decode(msg.Params, &params, func() interface{} {
+			if n := len(params.ContentChanges); n > 0 {
+				s.update(params.TextDocument.URI, params.ContentChanges[n-1].Text)
+			}
+			return nil
+		})
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		return s.decode(msg.Params, &params, func() interface{} {
+			delete(s.documents, params.TextDocument.URI)
+			s.notify("textDocument/publishDiagnostics",
+				PublishDiagnosticsParams{URI: params.TextDocument.URI, Diagnostics: []Diagnostic{}})
+			return nil
+		})
+	case "textDocument/hover":
+		var params TextDocumentPositionParams
+		return s.decode(msg.Params, &params, func() interface{} {
+			return s.hover(params)
+		})
+	case "textDocument/definition":
+		var params TextDocumentPositionParams
+		return s.decode(msg.Params, &params, func() interface{} {
+			return s.definition(params)
+		})
+	case "textDocument/references":
+		var params ReferenceParams
+		return s.decode(msg.Params, &params, func() interface{} {
+			return s.references(params)
+		})
+	case "textDocument/documentSymbol":
+		var params DocumentSymbolParams
+		return s.decode(msg.Params, &params, func() interface{} {
+			return s.documentSymbols(params)
+		})
+	case "textDocument/formatting":
+		var params DocumentFormattingParams
+		return s.decode(msg.Params, &params, func() interface{} {
+			return s.formatting(params)
+		})
+	case "textDocument/completion":
+		var params TextDocumentPositionParams
+		return s.decode(msg.Params, &params, func() interface{} {
+			return s.completion(params)
+		})
+	default:
+		if strings.HasPrefix(msg.Method, "$/") || msg.ID == nil {
+			// Optional notifications can be ignored.
+			return nil, nil
+		}
+		return nil, &responseError{Code: codeMethodNotFound, Message: "method not found: " + msg.Method}
+	}
+}
+
+// decode unmarshals params into target and then runs handler.
+func (s *Server) decode(params json.RawMessage, target interface{}, handler func() interface{}) (interface{}, *responseError) {
+	if err := json.Unmarshal(params, target); err != nil {
+		return nil, &responseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return handler(), nil
+}
+
+func (s *Server) initialize() InitializeResult {
+	var result InitializeResult
+	result.Capabilities = ServerCapabilities{
+		TextDocumentSync:       textDocumentSyncFull,
+		HoverProvider:          true,
+		DefinitionProvider:     true,
+		ReferencesProvider:     true,
+		DocumentSymbolProvider: true,
+		FormattingProvider:     true,
+		CompletionProvider:     &CompletionOptions{},
+	}
+	result.ServerInfo.Name = ServerName
+	result.ServerInfo.Version = s.version
+	return result
+}
+
+// update re-analyses a document and publishes its diagnostics.
+func (s *Server) update(uri string, text string) {
+	doc := newDocument(text)
+	s.documents[uri] = doc
+
+	diagnostics := []Diagnostic{}
+	for _, problem := range doc.analysis.Problems {
+		diagnostics = append(diagnostics, Diagnostic{
+			Range:    doc.tokenRange(problem.Token),
+			Severity: severityError,
+			Source:   ServerName,
+			Message:  problem.Message,
+		})
+	}
+
+	// Lint warnings are only meaningful for programs that compile.
+	if len(doc.analysis.Problems) == 0 {
+		for _, warning := range lint.Check(doc.analysis, lint.Config{}) {
+			diagnostics = append(diagnostics, Diagnostic{
+				Range:    doc.tokenRange(warning.Token),
+				Severity: severityWarning,
+				Code:     warning.Rule,
+				Source:   ServerName,
+				Message:  warning.Message,
+			})
+		}
+	}
+	s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: uri, Diagnostics: diagnostics})
+}
+
+// readMessage reads one message framed by a Content-Length header.
+func (s *Server) readMessage() ([]byte, error) {
+	headers, err := textproto.NewReader(s.in).ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+
+	length, err := strconv.Atoi(headers.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length: %w", err)
+	}
+	if length < 0 || length > maxMessageLength {
+		return nil, fmt.Errorf("invalid Content-Length: %d is not between 0 and %d", length, maxMessageLength)
+	}
+
+	content := make([]byte, length)
+	if _, err := io.ReadFull(s.in, content); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+func (s *Server) reply(id *json.RawMessage, result interface{}, rpcErr *responseError) {
+	if rpcErr != nil {
+		result = nil
+	}
+	s.write(response{JSONRPC: "2.0", ID: id, Result: result, Error: rpcErr})
+}
+
+func (s *Server) notify(method string, params interface{}) {
+	s.write(notification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+func (s *Server) write(value interface{}) {
+	content, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(content), content)
+}
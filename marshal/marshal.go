@@ -0,0 +1,365 @@
+// Package marshal serialises compiled bytecode so it can be written to disk by
+// `adl build` and loaded again without recompiling, and REPL sessions so they
+// can be resumed later.
+package marshal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/mislavperi/adl-lang/code"
+	"github.com/mislavperi/adl-lang/compiler"
+	"github.com/mislavperi/adl-lang/representation"
+	"github.com/mislavperi/adl-lang/vm"
+)
+
+// Magic identifies a compiled ADL file.
+const Magic = "ADLC"
+
+// Version is bumped whenever the encoding or the opcode set changes in a way
+// that makes older files unreadable.
+const Version = 1
+
+// Extension is the conventional file extension for compiled bytecode.
+const Extension = ".adlc"
+
+// maxLength bounds the strings and instructions a file can declare, so that a
+// corrupt length is reported rather than allocated.
+const maxLength = 1 << 30
+
+const (
+	tagInteger byte = iota + 1
+	tagString
+	tagCompiledFunction
+	tagBoolean
+	tagNull
+	tagArray
+	tagHash
+	tagClosure
+	tagBuiltin
+	tagError
+	tagUnset
+	tagFloat
+)
+
+// WriteBytecode encodes bytecode to w.
+func WriteBytecode(w io.Writer, bytecode *compiler.Bytecode) error {
+	enc := &encoder{w: bufio.NewWriter(w)}
+
+	enc.writeBytes([]byte(Magic))
+	enc.writeUvarint(Version)
+	enc.writeInstructions(bytecode.Instructions)
+	enc.writeUvarint(uint64(len(bytecode.Constants)))
+	for _, constant := range bytecode.Constants {
+		enc.writeValue(constant)
+	}
+
+	if enc.err != nil {
+		return enc.err
+	}
+	return enc.w.Flush()
+}
+
+// ReadBytecode decodes bytecode previously written by WriteBytecode.
+func ReadBytecode(r io.Reader) (*compiler.Bytecode, error) {
+	dec := &decoder{r: bufio.NewReader(r)}
+
+	magic := dec.readBytes(uint64(len(Magic)))
+	if dec.err == nil && string(magic) != Magic {
+		return nil, errors.New("not a compiled ADL file")
+	}
+
+	if version := dec.readUvarint(); dec.err == nil && version != Version {
+		return nil, fmt.Errorf("unsupported bytecode version %d, expected %d", version, Version)
+	}
+
+	instructions := dec.readInstructions()
+	count := dec.readUvarint()
+
+	constants := []representation.Representation{}
+	for i := uint64(0); i < count && dec.err == nil; i++ {
+		constants = append(constants, dec.readValue())
+	}
+
+	if dec.err != nil {
+		return nil, fmt.Errorf("reading bytecode: %w", dec.err)
+	}
+
+	bytecode := &compiler.Bytecode{Instructions: instructions, Constants: constants}
+	if err := verify(bytecode); err != nil {
+		return nil, fmt.Errorf("invalid bytecode: %w", err)
+	}
+	return bytecode, nil
+}
+
+// IsBytecode reports whether content starts with the compiled file header.
+func IsBytecode(content []byte) bool {
+	return bytes.HasPrefix(content, []byte(Magic))
+}
+
+type encoder struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (e *encoder) writeBytes(b []byte) {
+	if e.err != nil {
+		return
+	}
+	_, e.err = e.w.Write(b)
+}
+
+func (e *encoder) writeByte(b byte) {
+	e.writeBytes([]byte{b})
+}
+
+func (e *encoder) writeUvarint(v uint64) {
+	buf := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(buf, v)
+	e.writeBytes(buf[:n])
+}
+
+func (e *encoder) writeVarint(v int64) {
+	buf := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutVarint(buf, v)
+	e.writeBytes(buf[:n])
+}
+
+func (e *encoder) writeString(s string) {
+	e.writeUvarint(uint64(len(s)))
+	e.writeBytes([]byte(s))
+}
+
+func (e *encoder) writeInstructions(ins code.Instructions) {
+	e.writeUvarint(uint64(len(ins)))
+	e.writeBytes(ins)
+}
+
+func (e *encoder) writeValue(value representation.Representation) {
+	switch value := value.(type) {
+	case *representation.Integer:
+		e.writeByte(tagInteger)
+		e.writeVarint(value.Value)
+	case *representation.Float:
+		e.writeByte(tagFloat)
+		e.writeUvarint(math.Float64bits(value.Value))
+	case *representation.String:
+		e.writeByte(tagString)
+		e.writeString(value.Value)
+	case *representation.CompiledFunction:
+		e.writeByte(tagCompiledFunction)
+		e.writeInstructions(value.Instructions)
+		e.writeUvarint(uint64(value.NumLocals))
+		e.writeUvarint(uint64(value.NumParameters))
+	case *representation.Boolean:
+		e.writeByte(tagBoolean)
+		if value.Value {
+			e.writeByte(1)
+		} else {
+			e.writeByte(0)
+		}
+	case *representation.Null:
+		e.writeByte(tagNull)
+	case *representation.Array:
+		e.writeByte(tagArray)
+		e.writeValues(value.Elements)
+	case *representation.Hash:
+		e.writeByte(tagHash)
+		e.writeUvarint(uint64(len(value.Pairs)))
+		for _, pair := range value.Ordered() {
+			e.writeValue(pair.Key)
+			e.writeValue(pair.Value)
+		}
+	case *representation.Closure:
+		e.writeByte(tagClosure)
+		e.writeValue(value.Fn)
+		e.writeValues(value.Free)
+	case *representation.Builtin:
+		e.writeByte(tagBuiltin)
+		e.writeString(builtinName(value))
+	case *representation.Error:
+		e.writeByte(tagError)
+		e.writeString(value.Message)
+	case nil:
+		e.writeByte(tagUnset)
+	default:
+		if e.err == nil {
+			e.err = fmt.Errorf("cannot serialise value of type %s", value.Type())
+		}
+	}
+}
+
+func (e *encoder) writeValues(values []representation.Representation) {
+	e.writeUvarint(uint64(len(values)))
+	for _, value := range values {
+		e.writeValue(value)
+	}
+}
+
+// builtinName finds the name a builtin is registered under. Builtins are
+// stored by name because their functions cannot be serialised.
+func builtinName(builtin *representation.Builtin) string {
+	for _, def := range representation.Builtins {
+		if def.Builtin == builtin {
+			return def.Name
+		}
+	}
+	return ""
+}
+
+type decoder struct {
+	r   *bufio.Reader
+	err error
+}
+
+// readBytes reads n bytes. The buffer grows as the bytes arrive, so a length
+// beyond the end of the input fails without allocating all of it up front.
+func (d *decoder) readBytes(n uint64) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n > maxLength {
+		d.err = fmt.Errorf("length %d is too large", n)
+		return nil
+	}
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, d.r, int64(n)); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		d.err = err
+		return nil
+	}
+	return buf.Bytes()
+}
+
+func (d *decoder) readByte() byte {
+	if d.err != nil {
+		return 0
+	}
+	var b byte
+	b, d.err = d.r.ReadByte()
+	return b
+}
+
+func (d *decoder) readUvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	var v uint64
+	v, d.err = binary.ReadUvarint(d.r)
+	return v
+}
+
+func (d *decoder) readVarint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	var v int64
+	v, d.err = binary.ReadVarint(d.r)
+	return v
+}
+
+func (d *decoder) readString() string {
+	return string(d.readBytes(d.readUvarint()))
+}
+
+func (d *decoder) readInstructions() code.Instructions {
+	return code.Instructions(d.readBytes(d.readUvarint()))
+}
+
+func (d *decoder) readValue() representation.Representation {
+	tag := d.readByte()
+	if d.err != nil {
+		return nil
+	}
+
+	switch tag {
+	case tagInteger:
+		return &representation.Integer{Value: d.readVarint()}
+	case tagString:
+		return &representation.String{Value: d.readString()}
+	case tagCompiledFunction:
+		instructions := d.readInstructions()
+		numLocals := d.readUvarint()
+		numParameters := d.readUvarint()
+		return &representation.CompiledFunction{
+			Instructions:  instructions,
+			NumLocals:     int(numLocals),
+			NumParameters: int(numParameters),
+		}
+	case tagBoolean:
+		// The VM compares booleans and null by identity, so decoding must
+		// return its singletons.
+		if d.readByte() == 1 {
+			return vm.True
+		}
+		return vm.False
+	case tagNull:
+		return vm.Null
+	case tagArray:
+		return &representation.Array{Elements: d.readValues()}
+	case tagHash:
+		return d.readHash()
+	case tagClosure:
+		fn, ok := d.readValue().(*representation.CompiledFunction)
+		if !ok && d.err == nil {
+			d.err = errors.New("closure without a compiled function")
+		}
+		return &representation.Closure{Fn: fn, Free: d.readValues()}
+	case tagBuiltin:
+		name := d.readString()
+		builtin := representation.GetBuiltinByName(name)
+		if builtin == nil {
+			if d.err == nil {
+				d.err = fmt.Errorf("unknown builtin %q", name)
+			}
+			return nil
+		}
+		return builtin
+	case tagError:
+		return &representation.Error{Message: d.readString()}
+	case tagUnset:
+		return nil
+	case tagFloat:
+		return &representation.Float{Value: math.Float64frombits(d.readUvarint())}
+	default:
+		d.err = fmt.Errorf("unknown value tag %d", tag)
+		return nil
+	}
+}
+
+func (d *decoder) readValues() []representation.Representation {
+	count := d.readUvarint()
+
+	values := []representation.Representation{}
+	for i := uint64(0); i < count && d.err == nil; i++ {
+		values = append(values, d.readValue())
+	}
+	return values
+}
+
+func (d *decoder) readHash() representation.Representation {
+	count := d.readUvarint()
+
+	hash := representation.NewHash()
+	for i := uint64(0); i < count && d.err == nil; i++ {
+		key := d.readValue()
+		value := d.readValue()
+
+		hashKey, ok := representation.HashKeyOf(key)
+		if !ok {
+			if d.err == nil {
+				d.err = errors.New("hash key is not hashable")
+			}
+			return nil
+		}
+		hash.Set(hashKey, representation.HashPair{Key: key, Value: value})
+	}
+	return hash
+}
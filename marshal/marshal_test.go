@@ -0,0 +1,198 @@
+package marshal
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mislavperi/adl-lang/code"
+	"github.com/mislavperi/adl-lang/compiler"
+	"github.com/mislavperi/adl-lang/engine"
+	"github.com/mislavperi/adl-lang/lexer"
+	"github.com/mislavperi/adl-lang/parser"
+	"github.com/mislavperi/adl-lang/representation"
+)
+
+func TestBytecodeRoundTrip(t *testing.T) {
+	input := `let greet = fn(name) { "hello " + name }; greet("adl"); 42; 1.5;`
+
+	comp := compiler.New()
+	if err := comp.Compile(parser.New(lexer.New(input)).ParseProgram()); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	bytecode := comp.Bytecode()
+
+	var buf bytes.Buffer
+	if err := WriteBytecode(&buf, bytecode); err != nil {
+		t.Fatalf("WriteBytecode failed: %s", err)
+	}
+
+	if !IsBytecode(buf.Bytes()) {
+		t.Fatalf("encoded bytecode is missing the header")
+	}
+
+	decoded, err := ReadBytecode(&buf)
+	if err != nil {
+		t.Fatalf("ReadBytecode failed: %s", err)
+	}
+
+	if !bytes.Equal(decoded.Instructions, bytecode.Instructions) {
+		t.Errorf("instructions differ.\nwant=%q\ngot=%q", bytecode.Instructions, decoded.Instructions)
+	}
+
+	if len(decoded.Constants) != len(bytecode.Constants) {
+		t.Fatalf("wrong number of constants. want=%d, got=%d", len(bytecode.Constants), len(decoded.Constants))
+	}
+
+	for i, want := range bytecode.Constants {
+		got := decoded.Constants[i]
+		if got.Type() != want.Type() {
+			t.Errorf("constant %d has wrong type. want=%s, got=%s", i, want.Type(), got.Type())
+			continue
+		}
+
+		if fn, ok := want.(*representation.CompiledFunction); ok {
+			gotFn := got.(*representation.CompiledFunction)
+			if !bytes.Equal(fn.Instructions, gotFn.Instructions) ||
+				fn.NumLocals != gotFn.NumLocals || fn.NumParameters != gotFn.NumParameters {
+				t.Errorf("constant %d function differs", i)
+			}
+			continue
+		}
+
+		if got.Inspect() != want.Inspect() {
+			t.Errorf("constant %d differs. want=%s, got=%s", i, want.Inspect(), got.Inspect())
+		}
+	}
+}
+
+func TestReadBytecodeRejectsGarbage(t *testing.T) {
+	if _, err := ReadBytecode(bytes.NewBufferString("let x = 1;")); err == nil {
+		t.Errorf("expected error reading source as bytecode")
+	}
+}
+
+func TestReadBytecodeRejectsBadLengths(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+	}{
+		{"oversized", append([]byte(Magic+"\x01"), 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01)},
+		{"truncated", append([]byte(Magic+"\x01"), 0x80, 0x80, 0x10, 1, 2, 3)},
+	}
+
+	for _, tt := range tests {
+		if _, err := ReadBytecode(bytes.NewReader(tt.input)); err == nil {
+			t.Errorf("%s: expected error reading a bad length", tt.name)
+		}
+	}
+
+	// Sessions share the decoder: one string constant with an oversized length.
+	session := append([]byte(SessionMagic+"\x01"), 1, tagString, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01)
+	if _, err := ReadSession(bytes.NewReader(session)); err == nil {
+		t.Errorf("expected error reading a session with a bad length")
+	}
+}
+
+func TestReadBytecodeRejectsBadOperands(t *testing.T) {
+	program := func(instructions []byte, constants ...byte) []byte {
+		input := append([]byte(Magic+"\x01"), byte(len(instructions)))
+		input = append(input, instructions...)
+		return append(input, constants...)
+	}
+	function := []byte{tagCompiledFunction, 3, byte(code.OpGetLocal), 1, byte(code.OpReturnValue), 1, 1}
+
+	tests := []struct {
+		name  string
+		input []byte
+	}{
+		{"missing constant", program(code.Make(code.OpConstant, 5), 0)},
+		{"truncated operand", program([]byte{byte(code.OpConstant), 0}, 0)},
+		{"unknown opcode", program([]byte{0xfe}, 0)},
+		{"closure of a string", program(code.Make(code.OpClosure, 0, 0), 1, tagString, 1, 'a')},
+		{"missing builtin", program(code.Make(code.OpGetBuiltin, 255), 0)},
+		{"local in main program", program(code.Make(code.OpGetLocal, 0), 0)},
+		{"local beyond function", program(code.Make(code.OpClosure, 0, 0), append([]byte{1}, function...)...)},
+		{"jump beyond the end", program(code.Make(code.OpJump, 100), 0)},
+	}
+
+	for _, tt := range tests {
+		if _, err := ReadBytecode(bytes.NewReader(tt.input)); err == nil {
+			t.Errorf("%s: expected error reading bad operands", tt.name)
+		}
+	}
+}
+
+func TestSessionRoundTrip(t *testing.T) {
+	setup := `
+	let count = 3;
+	let name = "adl";
+	let flags = [true, false, if (false) { 1 }];
+	let table = {"a": 1, 2: "b", true: [len]};
+	let adder = fn(x) { fn(y) { x + y } };
+	let addTwo = adder(2);
+	let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } };
+	let len = fn(x) { 0 };
+	`
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"count", "3"},
+		{"name", "adl"},
+		{"flags[0] == true", "true"},
+		{"flags[2]", "null"},
+		{"if (flags[1]) { 1 } else { 2 }", "2"},
+		{`table["a"] + 1`, "2"},
+		{"table[2]", "b"},
+		{`table[true][0]("four")`, "4"},
+		{"addTwo(40)", "42"},
+		{"adder(1)(1)", "2"},
+		{"fib(10)", "55"},
+		{"len([1, 2])", "0"},
+		{"first([count])", "3"},
+		{"let more = count + 1; more", "4"},
+	}
+
+	original := engine.NewVM(nil)
+	if _, err := original.Run(parser.New(lexer.New(setup)).ParseProgram()); err != nil {
+		t.Fatalf("setup failed: %s", err)
+	}
+
+	var buf bytes.Buffer
+	err := WriteSession(&buf, &Session{
+		Constants:   original.Constants(),
+		Globals:     original.Globals(),
+		SymbolTable: original.SymbolTable(),
+	})
+	if err != nil {
+		t.Fatalf("WriteSession failed: %s", err)
+	}
+
+	for _, tt := range tests {
+		saved, err := ReadSession(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("ReadSession failed: %s", err)
+		}
+
+		restored := engine.NewVMWithState(nil, saved.Constants, saved.Globals, saved.SymbolTable)
+		result, err := restored.Run(parser.New(lexer.New(tt.input)).ParseProgram())
+		if err != nil {
+			t.Errorf("%s: run failed: %s", tt.input, err)
+			continue
+		}
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: wrong result. want=%s, got=%s", tt.input, tt.expected, result.Inspect())
+		}
+	}
+}
+
+func TestReadSessionRejectsBytecode(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteBytecode(&buf, &compiler.Bytecode{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadSession(&buf); err == nil {
+		t.Errorf("expected error reading bytecode as a session")
+	}
+}
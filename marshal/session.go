@@ -0,0 +1,114 @@
+package marshal
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/mislavperi/adl-lang/representation"
+	symboltable "github.com/mislavperi/adl-lang/symbol_table"
+	"github.com/mislavperi/adl-lang/vm"
+)
+
+// SessionMagic identifies a saved REPL session.
+const SessionMagic = "ADLS"
+
+// Session is the state a VM engine keeps between programs: the constants
+// compiled so far, the global store and the global symbol table.
+type Session struct {
+	Constants   []representation.Representation
+	Globals     []representation.Representation
+	SymbolTable *symboltable.SymbolTable
+}
+
+// WriteSession encodes session to w. Only the globals that the symbol table
+// has defined are written.
+func WriteSession(w io.Writer, session *Session) error {
+	enc := &encoder{w: bufio.NewWriter(w)}
+
+	enc.writeBytes([]byte(SessionMagic))
+	enc.writeUvarint(Version)
+
+	enc.writeValues(session.Constants)
+
+	table := session.SymbolTable
+	if table.NumDefinitions > len(session.Globals) {
+		return fmt.Errorf("symbol table defines %d globals, store holds %d",
+			table.NumDefinitions, len(session.Globals))
+	}
+	enc.writeValues(session.Globals[:table.NumDefinitions])
+
+	// Builtins are not written: they are defined again when the session is
+	// read, so it keeps working if the set of builtins changes. Sort the rest
+	// so that saving the same session twice gives the same file.
+	symbols := []symboltable.Symbol{}
+	for _, symbol := range table.Store {
+		if symbol.Scope == symboltable.GlobalScope {
+			symbols = append(symbols, symbol)
+		}
+	}
+	sort.Slice(symbols, func(i int, j int) bool {
+		return symbols[i].Name < symbols[j].Name
+	})
+
+	enc.writeUvarint(uint64(len(symbols)))
+	for _, symbol := range symbols {
+		enc.writeString(symbol.Name)
+		enc.writeUvarint(uint64(symbol.Index))
+	}
+
+	if enc.err != nil {
+		return enc.err
+	}
+	return enc.w.Flush()
+}
+
+// ReadSession decodes a session previously written by WriteSession.
+func ReadSession(r io.Reader) (*Session, error) {
+	dec := &decoder{r: bufio.NewReader(r)}
+
+	magic := dec.readBytes(uint64(len(SessionMagic)))
+	if dec.err == nil && string(magic) != SessionMagic {
+		return nil, errors.New("not a saved ADL session")
+	}
+
+	if version := dec.readUvarint(); dec.err == nil && version != Version {
+		return nil, fmt.Errorf("unsupported session version %d, expected %d", version, Version)
+	}
+
+	constants := dec.readValues()
+	defined := dec.readValues()
+	if len(defined) > vm.GlobalsSize {
+		return nil, fmt.Errorf("session defines %d globals, at most %d are allowed", len(defined), vm.GlobalsSize)
+	}
+
+	globals := make([]representation.Representation, vm.GlobalsSize)
+	copy(globals, defined)
+
+	table := symboltable.NewSymbolTable()
+	for index, builtin := range representation.Builtins {
+		table.DefineBuiltin(index, builtin.Name)
+	}
+	table.NumDefinitions = len(defined)
+
+	count := dec.readUvarint()
+	for i := uint64(0); i < count && dec.err == nil; i++ {
+		name := dec.readString()
+		index := dec.readUvarint()
+		if index >= uint64(len(defined)) && dec.err == nil {
+			dec.err = fmt.Errorf("global %q has index %d beyond the %d defined", name, index, len(defined))
+		}
+		table.Store[name] = symboltable.Symbol{Name: name, Scope: symboltable.GlobalScope, Index: int(index)}
+	}
+
+	if dec.err != nil {
+		return nil, fmt.Errorf("reading session: %w", dec.err)
+	}
+	if err := verifyFunctions(constants); err != nil {
+		return nil, fmt.Errorf("invalid session: %w", err)
+	}
+
+	return &Session{Constants: constants, Globals: globals, SymbolTable: table}, nil
+}
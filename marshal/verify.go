@@ -0,0 +1,84 @@
+package marshal
+
+import (
+	"fmt"
+
+	"github.com/mislavperi/adl-lang/code"
+	"github.com/mislavperi/adl-lang/compiler"
+	"github.com/mislavperi/adl-lang/representation"
+)
+
+// verify checks that the instructions of bytecode are whole and only refer
+// to constants, builtins, locals and jump targets that exist, so that a
+// corrupt file is reported when it is read rather than crashing the VM.
+func verify(bytecode *compiler.Bytecode) error {
+	// The main program has no frame of its own, so it has no locals.
+	if err := verifyInstructions(bytecode.Instructions, bytecode.Constants, 0); err != nil {
+		return fmt.Errorf("main program: %w", err)
+	}
+	return verifyFunctions(bytecode.Constants)
+}
+
+// verifyFunctions verifies the compiled functions among constants.
+func verifyFunctions(constants []representation.Representation) error {
+	for i, constant := range constants {
+		fn, ok := constant.(*representation.CompiledFunction)
+		if !ok {
+			continue
+		}
+		if fn.NumParameters > fn.NumLocals {
+			return fmt.Errorf("constant %d: %d parameters but %d locals", i, fn.NumParameters, fn.NumLocals)
+		}
+		if err := verifyInstructions(fn.Instructions, constants, fn.NumLocals); err != nil {
+			return fmt.Errorf("constant %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func verifyInstructions(ins code.Instructions, constants []representation.Representation, numLocals int) error {
+	for i := 0; i < len(ins); {
+		def, err := code.Lookup(ins[i])
+		if err != nil {
+			return fmt.Errorf("offset %d: %w", i, err)
+		}
+
+		width := 0
+		for _, w := range def.OperandWidths {
+			width += w
+		}
+		if i+1+width > len(ins) {
+			return fmt.Errorf("offset %d: %s is missing its operands", i, def.Name)
+		}
+		operands, read := code.ReadOperands(def, ins[i+1:])
+
+		switch code.Opcode(ins[i]) {
+		case code.OpConstant:
+			if operands[0] >= len(constants) {
+				return fmt.Errorf("offset %d: constant %d of %d", i, operands[0], len(constants))
+			}
+		case code.OpClosure, code.OpClosureWide:
+			if operands[0] >= len(constants) {
+				return fmt.Errorf("offset %d: constant %d of %d", i, operands[0], len(constants))
+			}
+			if _, ok := constants[operands[0]].(*representation.CompiledFunction); !ok {
+				return fmt.Errorf("offset %d: closure of constant %d, which is not a function", i, operands[0])
+			}
+		case code.OpGetBuiltin:
+			if operands[0] >= len(representation.Builtins) {
+				return fmt.Errorf("offset %d: builtin %d of %d", i, operands[0], len(representation.Builtins))
+			}
+		case code.OpGetLocal, code.OpSetLocal, code.OpGetLocalWide, code.OpSetLocalWide:
+			if operands[0] >= numLocals {
+				return fmt.Errorf("offset %d: local %d of %d", i, operands[0], numLocals)
+			}
+		case code.OpJump, code.OpJumpNotTruthy:
+			if operands[0] > len(ins) {
+				return fmt.Errorf("offset %d: jump to %d beyond the end at %d", i, operands[0], len(ins))
+			}
+		}
+
+		i += 1 + read
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package parser
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/mislavperi/adl-lang/ast"
 	"github.com/mislavperi/adl-lang/lexer"
@@ -37,6 +38,7 @@ var precedences = map[token.TokenType]int{
 type Parser struct {
 	lexer          *lexer.Lexer
 	errors         []string
+	errorTokens    []token.Token
 	curToken       token.Token
 	peekToken      token.Token
 	prefixParseFns map[token.TokenType]prefixParseFn
@@ -68,6 +70,7 @@ func New(l *lexer.Lexer) *Parser {
 func (p *Parser) initPrefixParseFns() {
 	p.registerPrefix(token.IDENTIFER, p.parseIdentifier)
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
@@ -115,6 +118,22 @@ func (p *Parser) Errors() []string {
 	return p.errors
 }
 
+// Error is a parse error and the token it was reported at.
+type Error struct {
+	Message string
+	Token   token.Token
+}
+
+// DetailedErrors returns the same errors as Errors along with where they
+// occurred.
+func (p *Parser) DetailedErrors() []Error {
+	detailed := make([]Error, len(p.errors))
+	for i, msg := range p.errors {
+		detailed[i] = Error{Message: msg, Token: p.errorTokens[i]}
+	}
+	return detailed
+}
+
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.curToken.Type {
 	case token.LET:
@@ -184,6 +203,19 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	return literal
 }
 
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	literal := &ast.FloatLiteral{BaseNode: ast.BaseNode{Token: p.curToken}}
+
+	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
+	if err != nil {
+		p.errorf("could not parse %q as float", p.curToken.Literal)
+		return nil
+	}
+
+	literal.Value = value
+	return literal
+}
+
 func (p *Parser) parseIdentifier() ast.Expression {
 	return &ast.Identifier{BaseNode: ast.BaseNode{Token: p.curToken}, Value: p.curToken.Literal}
 }
@@ -271,7 +303,31 @@ func (p *Parser) parseFnLiteral() ast.Expression {
 }
 
 func (p *Parser) parseStringLiteral() ast.Expression {
-	return &ast.StringLiteral{BaseNode: ast.BaseNode{Token: p.curToken}, Value: p.curToken.Literal}
+	return &ast.StringLiteral{BaseNode: ast.BaseNode{Token: p.curToken}, Value: unescape(p.curToken.Literal)}
+}
+
+// escapes are the characters a backslash in a string stands for. A backslash
+// before any other character is kept, so patterns such as "\d" need no
+// second backslash.
+var escapes = map[byte]byte{'n': '\n', 't': '\t', 'r': '\r', '"': '"', '\\': '\\'}
+
+func unescape(literal string) string {
+	if !strings.ContainsRune(literal, '\\') {
+		return literal
+	}
+
+	var value strings.Builder
+	for i := 0; i < len(literal); i++ {
+		if literal[i] == '\\' && i+1 < len(literal) {
+			if escaped, ok := escapes[literal[i+1]]; ok {
+				value.WriteByte(escaped)
+				i++
+				continue
+			}
+		}
+		value.WriteByte(literal[i])
+	}
+	return value.String()
 }
 
 func (p *Parser) parseArrayLiteral() ast.Expression {
@@ -298,6 +354,7 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 		value := p.parseExpression(LOWEST)
 
 		hash.Pairs[key] = value
+		hash.Keys = append(hash.Keys, key)
 
 		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
 			return nil
@@ -409,14 +466,30 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	return expression
 }
 
+// parseIndexExpression parses left[index], or the slice left[start:end] in
+// which either bound can be left out.
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
-	exp := &ast.IndexExpression{
-		BaseNode: ast.BaseNode{Token: p.curToken},
-		Left:     left,
+	bracket := p.curToken
+
+	var index ast.Expression
+	if !p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		index = p.parseExpression(LOWEST)
 	}
 
+	if !p.peekTokenIs(token.COLON) {
+		if !p.expectPeek(token.RBRACKET) {
+			return nil
+		}
+		return &ast.IndexExpression{BaseNode: ast.BaseNode{Token: bracket}, Left: left, Index: index}
+	}
+
+	exp := &ast.SliceExpression{BaseNode: ast.BaseNode{Token: bracket}, Left: left, Start: index}
 	p.nextToken()
-	exp.Index = p.parseExpression(LOWEST)
+	if !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+		exp.End = p.parseExpression(LOWEST)
+	}
 
 	if !p.expectPeek(token.RBRACKET) {
 		return nil
@@ -460,7 +533,7 @@ func (p *Parser) peekTokenIs(t token.TokenType) bool {
 }
 
 func (p *Parser) peekError(t token.TokenType) {
-	p.errorf("expected next token to be %s, got %s instead", t, p.peekToken.Type)
+	p.errorAt(p.peekToken, "expected next token to be %s, got %s instead", t, p.peekToken.Type)
 }
 
 func (p *Parser) expectPeek(t token.TokenType) bool {
@@ -474,8 +547,13 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 }
 
 func (p *Parser) errorf(format string, args ...interface{}) {
+	p.errorAt(p.curToken, format, args...)
+}
+
+func (p *Parser) errorAt(tok token.Token, format string, args ...interface{}) {
 	msg := fmt.Sprintf(format, args...)
 	p.errors = append(p.errors, msg)
+	p.errorTokens = append(p.errorTokens, tok)
 }
 
 func (p *Parser) skipSemicolons() {
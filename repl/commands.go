@@ -0,0 +1,222 @@
+package repl
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/mislavperi/adl-lang/engine"
+	"github.com/mislavperi/adl-lang/marshal"
+	"github.com/mislavperi/adl-lang/representation"
+	symboltable "github.com/mislavperi/adl-lang/symbol_table"
+)
+
+// metaCommand is a REPL command starting with a colon, handled by the REPL
+// itself rather than evaluated as ADL.
+type metaCommand struct {
+	name    string
+	args    string
+	summary string
+	run     func(s *Session, arg string) error
+}
+
+var metaCommands []metaCommand
+
+func init() {
+	metaCommands = []metaCommand{
+		{"help", "", "show this help", (*Session).help},
+		{"quit", "", "leave the REPL", (*Session).quit},
+		{"reset", "", "forget every definition", (*Session).reset},
+		{"globals", "", "list global bindings and their values", (*Session).globals},
+		{"dis", "<expr>", "show the bytecode compiled for expr", (*Session).disassemble},
+		{"ast", "<expr>", "show the syntax tree parsed from expr", (*Session).dumpAST},
+		{"time", "[on|off]", "toggle reporting how long each entry takes", (*Session).toggleTiming},
+		{"load", "<file.adl>", "run a file in this session", (*Session).load},
+		{"save", "<file>", "save every definition to file", (*Session).save},
+		{"restore", "<file>", "replace the session with one saved to file", (*Session).restore},
+	}
+}
+
+// Command runs a meta-command line such as ":dis 1 + 2".
+func (s *Session) Command(line string) error {
+	name, arg, _ := strings.Cut(strings.TrimPrefix(line, ":"), " ")
+	arg = strings.TrimSpace(arg)
+
+	for _, cmd := range metaCommands {
+		if cmd.name == name || (name == "q" && cmd.name == "quit") {
+			return cmd.run(s, arg)
+		}
+	}
+
+	fmt.Fprintf(s.out, "unknown command :%s, type :help for a list\n", name)
+	return nil
+}
+
+func (s *Session) help(string) error {
+	for _, cmd := range metaCommands {
+		usage := ":" + cmd.name
+		if cmd.args != "" {
+			usage += " " + cmd.args
+		}
+		fmt.Fprintf(s.out, "  %-18s %s\n", usage, cmd.summary)
+	}
+	fmt.Fprintln(s.out, "Entries with unclosed brackets continue on the next line; an empty line submits them.")
+	return nil
+}
+
+func (s *Session) quit(string) error {
+	return errQuit
+}
+
+func (s *Session) reset(string) error {
+	s.engine = engine.NewVM(s.host)
+	fmt.Fprintln(s.out, "session reset")
+	return nil
+}
+
+func (s *Session) globals(string) error {
+	symbols := []symboltable.Symbol{}
+	for _, symbol := range s.engine.SymbolTable().Store {
+		if symbol.Scope == symboltable.GlobalScope {
+			symbols = append(symbols, symbol)
+		}
+	}
+
+	sort.Slice(symbols, func(i int, j int) bool {
+		return symbols[i].Index < symbols[j].Index
+	})
+
+	globals := s.engine.Globals()
+	for _, symbol := range symbols {
+		value := "<unset>"
+		if global := globals[symbol.Index]; global != nil {
+			value = global.Inspect()
+		}
+		fmt.Fprintf(s.out, "%s = %s\n", symbol.Name, value)
+	}
+	return nil
+}
+
+func (s *Session) disassemble(arg string) error {
+	program, ok := s.parse(arg)
+	if !ok {
+		return nil
+	}
+
+	bytecode, err := s.engine.Compile(program)
+	if err != nil {
+		fmt.Fprintf(s.out, "Compilation failed:\n%s\n", err)
+		return nil
+	}
+
+	fmt.Fprint(s.out, bytecode.Instructions.String())
+
+	for index := len(s.engine.Constants()); index < len(bytecode.Constants); index++ {
+		if fn, ok := bytecode.Constants[index].(*representation.CompiledFunction); ok {
+			fmt.Fprintf(s.out, "\nconstant %d: function, %d parameters, %d locals\n", index, fn.NumParameters, fn.NumLocals)
+			fmt.Fprint(s.out, fn.Instructions.String())
+		}
+	}
+	return nil
+}
+
+func (s *Session) dumpAST(arg string) error {
+	program, ok := s.parse(arg)
+	if !ok {
+		return nil
+	}
+
+	fmt.Fprint(s.out, Dump(program))
+	return nil
+}
+
+func (s *Session) toggleTiming(arg string) error {
+	switch arg {
+	case "":
+		s.timing = !s.timing
+	case "on":
+		s.timing = true
+	case "off":
+		s.timing = false
+	default:
+		fmt.Fprintf(s.out, "usage: :time [on|off]\n")
+		return nil
+	}
+
+	state := "off"
+	if s.timing {
+		state = "on"
+	}
+	fmt.Fprintf(s.out, "timing %s\n", state)
+	return nil
+}
+
+func (s *Session) load(arg string) error {
+	if arg == "" {
+		fmt.Fprintln(s.out, "usage: :load <file.adl>")
+		return nil
+	}
+
+	content, err := os.ReadFile(arg)
+	if err != nil {
+		fmt.Fprintf(s.out, "%s\n", err)
+		return nil
+	}
+
+	return s.Eval(string(content))
+}
+
+func (s *Session) save(arg string) error {
+	if arg == "" {
+		fmt.Fprintln(s.out, "usage: :save <file>")
+		return nil
+	}
+
+	file, err := os.Create(arg)
+	if err != nil {
+		fmt.Fprintf(s.out, "%s\n", err)
+		return nil
+	}
+	defer file.Close()
+
+	err = marshal.WriteSession(file, &marshal.Session{
+		Constants:   s.engine.Constants(),
+		Globals:     s.engine.Globals(),
+		SymbolTable: s.engine.SymbolTable(),
+	})
+	if err == nil {
+		err = file.Close()
+	}
+	if err != nil {
+		fmt.Fprintf(s.out, "saving session: %s\n", err)
+		return nil
+	}
+
+	fmt.Fprintf(s.out, "session saved to %s\n", arg)
+	return nil
+}
+
+func (s *Session) restore(arg string) error {
+	if arg == "" {
+		fmt.Fprintln(s.out, "usage: :restore <file>")
+		return nil
+	}
+
+	file, err := os.Open(arg)
+	if err != nil {
+		fmt.Fprintf(s.out, "%s\n", err)
+		return nil
+	}
+	defer file.Close()
+
+	saved, err := marshal.ReadSession(file)
+	if err != nil {
+		fmt.Fprintf(s.out, "%s\n", err)
+		return nil
+	}
+
+	s.engine = engine.NewVMWithState(s.host, saved.Constants, saved.Globals, saved.SymbolTable)
+	fmt.Fprintf(s.out, "session restored from %s\n", arg)
+	return nil
+}
@@ -0,0 +1,115 @@
+package repl
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/mislavperi/adl-lang/ast"
+)
+
+// Dump renders node as an indented tree, one node per line.
+func Dump(node ast.Node) string {
+	var out bytes.Buffer
+	dump(&out, node, 0)
+	return out.String()
+}
+
+func dump(out *bytes.Buffer, node ast.Node, depth int) {
+	line := func(format string, args ...interface{}) {
+		out.WriteString(strings.Repeat("  ", depth))
+		fmt.Fprintf(out, format, args...)
+		out.WriteString("\n")
+	}
+
+	switch node := node.(type) {
+	case *ast.Program:
+		line("Program")
+		for _, s := range node.Statements {
+			dump(out, s, depth+1)
+		}
+	case *ast.LetStatement:
+		line("LetStatement %s", node.Name.Value)
+		dump(out, node.Value, depth+1)
+	case *ast.ReturnStatement:
+		line("ReturnStatement")
+		dump(out, node.ReturnValue, depth+1)
+	case *ast.ExpressionStatement:
+		line("ExpressionStatement")
+		dump(out, node.Expression, depth+1)
+	case *ast.BlockStatement:
+		line("BlockStatement")
+		for _, s := range node.Statements {
+			dump(out, s, depth+1)
+		}
+	case *ast.Identifier:
+		line("Identifier %s", node.Value)
+	case *ast.IntegerLiteral:
+		line("IntegerLiteral %d", node.Value)
+	case *ast.FloatLiteral:
+		line("FloatLiteral %s", node.Token.Literal)
+	case *ast.StringLiteral:
+		line("StringLiteral %q", node.Value)
+	case *ast.Boolean:
+		line("Boolean %t", node.Value)
+	case *ast.PrefixExpression:
+		line("PrefixExpression %s", node.Operator)
+		dump(out, node.Right, depth+1)
+	case *ast.InfixExpression:
+		line("InfixExpression %s", node.Operator)
+		dump(out, node.Left, depth+1)
+		dump(out, node.Right, depth+1)
+	case *ast.IfExpression:
+		line("IfExpression")
+		dump(out, node.Condition, depth+1)
+		dump(out, node.Consequence, depth+1)
+		if node.Alternative != nil {
+			dump(out, node.Alternative, depth+1)
+		}
+	case *ast.FnLiteral:
+		params := make([]string, len(node.Parameters))
+		for i, p := range node.Parameters {
+			params[i] = p.Value
+		}
+		if node.Name != "" {
+			line("FnLiteral %s(%s)", node.Name, strings.Join(params, ", "))
+		} else {
+			line("FnLiteral (%s)", strings.Join(params, ", "))
+		}
+		dump(out, node.Body, depth+1)
+	case *ast.CallExpression:
+		line("CallExpression")
+		dump(out, node.Function, depth+1)
+		for _, arg := range node.Arguments {
+			dump(out, arg, depth+1)
+		}
+	case *ast.ArrayLiteral:
+		line("ArrayLiteral")
+		for _, el := range node.Elements {
+			dump(out, el, depth+1)
+		}
+	case *ast.IndexExpression:
+		line("IndexExpression")
+		dump(out, node.Left, depth+1)
+		dump(out, node.Index, depth+1)
+	case *ast.SliceExpression:
+		line("SliceExpression")
+		dump(out, node.Left, depth+1)
+		if node.Start != nil {
+			dump(out, node.Start, depth+1)
+		}
+		if node.End != nil {
+			dump(out, node.End, depth+1)
+		}
+	case *ast.HashLiteral:
+		line("HashLiteral")
+		for _, key := range node.OrderedKeys() {
+			dump(out, key, depth+1)
+			dump(out, node.Pairs[key], depth+2)
+		}
+	case nil:
+		line("<nil>")
+	default:
+		line("%T", node)
+	}
+}
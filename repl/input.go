@@ -0,0 +1,191 @@
+package repl
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/mislavperi/adl-lang/repl/lineedit"
+	"github.com/mislavperi/adl-lang/representation"
+	symboltable "github.com/mislavperi/adl-lang/symbol_table"
+	"github.com/mislavperi/adl-lang/token"
+)
+
+// HISTORY_FILE is the file in the home directory that keeps entries between
+// sessions. The ADL_HISTORY environment variable overrides its location.
+const HISTORY_FILE = ".adl_history"
+
+// lineReader reads one line of input after showing prompt.
+type lineReader interface {
+	ReadLine(prompt string) (string, error)
+	AddHistory(entry string)
+
+	// ReadInput reads a line a program asked for, after the program printed
+	// partial on the current line.
+	ReadInput(partial string) (string, error)
+}
+
+// scannerReader reads plain lines from a pipe or file.
+type scannerReader struct {
+	scanner *bufio.Scanner
+	out     io.Writer
+}
+
+func (r *scannerReader) ReadLine(prompt string) (string, error) {
+	fmt.Fprint(r.out, prompt)
+	if !r.scanner.Scan() {
+		if err := r.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return r.scanner.Text(), nil
+}
+
+func (r *scannerReader) AddHistory(string) {}
+
+func (r *scannerReader) ReadInput(string) (string, error) {
+	return r.ReadLine("")
+}
+
+// editorReader reads lines from a terminal with line editing.
+type editorReader struct {
+	editor *lineedit.Editor
+}
+
+func (r *editorReader) ReadLine(prompt string) (string, error) {
+	return r.editor.ReadLine(prompt)
+}
+
+func (r *editorReader) AddHistory(entry string) {
+	// A history file that cannot be written should not end the session.
+	r.editor.History.Add(entry)
+}
+
+func (r *editorReader) ReadInput(partial string) (string, error) {
+	// The editor clears the line when it draws it, so what the program
+	// printed is drawn again as the prompt.
+	return r.editor.ReadLine(partial)
+}
+
+// programInput is the Stdin of programs run in the REPL. It reads the lines
+// the user types next, so that `input` and `read_line` work as they do when
+// a file is run.
+type programInput struct {
+	reader  lineReader
+	output  *lineTail
+	pending []byte
+}
+
+func (p *programInput) Read(b []byte) (int, error) {
+	if len(p.pending) == 0 {
+		line, err := p.reader.ReadInput(p.output.partial)
+		if err != nil {
+			return 0, err
+		}
+		p.output.partial = ""
+		p.pending = []byte(line + "\n")
+	}
+	n := copy(b, p.pending)
+	p.pending = p.pending[n:]
+	return n, nil
+}
+
+// lineTail passes output through and remembers what was written after the
+// last newline.
+type lineTail struct {
+	w       io.Writer
+	partial string
+}
+
+func (t *lineTail) Write(b []byte) (int, error) {
+	if i := bytes.LastIndexByte(b, '\n'); i >= 0 {
+		t.partial = string(b[i+1:])
+	} else {
+		t.partial += string(b)
+	}
+	return t.w.Write(b)
+}
+
+// newLineReader picks line editing when in is a terminal.
+func newLineReader(in io.Reader, out io.Writer, session *Session) lineReader {
+	if !lineedit.IsTerminal(in) {
+		return &scannerReader{scanner: bufio.NewScanner(in), out: out}
+	}
+
+	editor := lineedit.New(in, out)
+	if path := historyPath(); path != "" {
+		if history, err := lineedit.LoadHistory(path); err == nil {
+			editor.History = history
+		}
+	}
+	editor.Complete = session.Complete
+	return &editorReader{editor: editor}
+}
+
+func historyPath() string {
+	if path := os.Getenv("ADL_HISTORY"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, HISTORY_FILE)
+}
+
+// Complete returns the keywords, builtins and globals that start with the
+// word before pos, or the meta-commands when the line starts with a colon.
+func (s *Session) Complete(line []rune, pos int) (int, []string) {
+	start := pos
+	for start > 0 && isWordRune(line[start-1]) {
+		start--
+	}
+	prefix := string(line[start:pos])
+
+	if strings.HasPrefix(string(line[:pos]), ":") && !strings.Contains(string(line[:pos]), " ") {
+		names := []string{}
+		for _, cmd := range metaCommands {
+			names = append(names, cmd.name)
+		}
+		return start, matchPrefix(names, prefix)
+	}
+
+	if prefix == "" {
+		return start, nil
+	}
+
+	names := token.Keywords()
+	for _, builtin := range representation.Builtins {
+		names = append(names, builtin.Name)
+	}
+	for name, symbol := range s.engine.SymbolTable().Store {
+		if symbol.Scope == symboltable.GlobalScope {
+			names = append(names, name)
+		}
+	}
+	return start, matchPrefix(names, prefix)
+}
+
+func matchPrefix(names []string, prefix string) []string {
+	seen := map[string]bool{}
+	matches := []string{}
+	for _, name := range names {
+		if strings.HasPrefix(name, prefix) && !seen[name] {
+			seen[name] = true
+			matches = append(matches, name)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
@@ -0,0 +1,445 @@
+// Package lineedit is a small terminal line editor for the REPL, with cursor
+// movement, persistent history, reverse search and tab completion.
+package lineedit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+
+	"golang.org/x/term"
+)
+
+// ErrInterrupted is returned by ReadLine when the user presses Ctrl-C.
+var ErrInterrupted = errors.New("interrupted")
+
+// CompleteFunc returns the candidates for the word ending at pos in line,
+// along with the index where that word starts.
+type CompleteFunc func(line []rune, pos int) (start int, candidates []string)
+
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyCtrlG     = 7
+	keyBackspace = 8
+	keyTab       = 9
+	keyLineFeed  = 10
+	keyCtrlK     = 11
+	keyCtrlL     = 12
+	keyEnter     = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlR     = 18
+	keyCtrlU     = 21
+	keyCtrlW     = 23
+	keyEscape    = 27
+	keyDelete    = 127
+
+	// Escape sequences are decoded into keys outside the rune range.
+	keyUp = unicode.MaxRune + 1 + iota
+	keyDown
+	keyLeft
+	keyRight
+	keyHome
+	keyEnd
+	keyForwardDelete
+	keyUnknown
+)
+
+// Editor reads lines from a terminal.
+type Editor struct {
+	in  *bufio.Reader
+	out io.Writer
+	fd  int
+
+	History  *History
+	Complete CompleteFunc
+
+	// lastWasTab records a tab that could not complete, so that a second
+	// tab lists the candidates.
+	lastWasTab bool
+}
+
+// New creates an editor reading keys from in. When in is a terminal it is
+// switched to raw mode while a line is being read.
+func New(in io.Reader, out io.Writer) *Editor {
+	fd := -1
+	if file, ok := in.(*os.File); ok && term.IsTerminal(int(file.Fd())) {
+		fd = int(file.Fd())
+	}
+
+	return &Editor{in: bufio.NewReader(in), out: out, fd: fd, History: NewHistory()}
+}
+
+// IsTerminal reports whether in is an interactive terminal.
+func IsTerminal(in io.Reader) bool {
+	file, ok := in.(*os.File)
+	return ok && term.IsTerminal(int(file.Fd()))
+}
+
+// ReadLine shows prompt and returns the line the user entered. It returns
+// io.EOF on Ctrl-D at an empty line and ErrInterrupted on Ctrl-C.
+func (e *Editor) ReadLine(prompt string) (string, error) {
+	if e.fd >= 0 {
+		state, err := term.MakeRaw(e.fd)
+		if err != nil {
+			return "", err
+		}
+		defer term.Restore(e.fd, state)
+	}
+
+	s := &lineState{prompt: prompt, historyIndex: e.History.Len()}
+	e.refresh(s)
+
+	for {
+		key, err := e.readKey()
+		if err != nil {
+			if err == io.EOF && len(s.line) > 0 {
+				e.newline()
+				return string(s.line), nil
+			}
+			return "", err
+		}
+
+		if key != keyTab {
+			e.lastWasTab = false
+		}
+
+		switch key {
+		case keyEnter, keyLineFeed:
+			e.moveToEnd(s)
+			e.newline()
+			return string(s.line), nil
+		case keyCtrlC:
+			fmt.Fprint(e.out, "^C")
+			e.newline()
+			return "", ErrInterrupted
+		case keyCtrlD:
+			if len(s.line) == 0 {
+				e.newline()
+				return "", io.EOF
+			}
+			s.deleteForward()
+		case keyBackspace, keyDelete:
+			s.deleteBackward()
+		case keyForwardDelete:
+			s.deleteForward()
+		case keyLeft, keyCtrlB:
+			if s.pos > 0 {
+				s.pos--
+			}
+		case keyRight, keyCtrlF:
+			if s.pos < len(s.line) {
+				s.pos++
+			}
+		case keyHome, keyCtrlA:
+			s.pos = 0
+		case keyEnd, keyCtrlE:
+			s.pos = len(s.line)
+		case keyCtrlK:
+			s.line = s.line[:s.pos]
+		case keyCtrlU:
+			s.line = append([]rune{}, s.line[s.pos:]...)
+			s.pos = 0
+		case keyCtrlW:
+			s.deleteWord()
+		case keyCtrlL:
+			fmt.Fprint(e.out, "\x1b[H\x1b[2J")
+		case keyUp, keyCtrlP:
+			e.historyMove(s, -1)
+		case keyDown, keyCtrlN:
+			e.historyMove(s, 1)
+		case keyTab:
+			e.complete(s)
+		case keyCtrlR:
+			if accepted, err := e.reverseSearch(s); accepted || err != nil {
+				e.newline()
+				return string(s.line), err
+			}
+		case keyEscape, keyUnknown:
+		default:
+			if unicode.IsPrint(rune(key)) {
+				s.insert([]rune{rune(key)})
+			}
+		}
+
+		e.refresh(s)
+	}
+}
+
+// lineState is the line being edited.
+type lineState struct {
+	prompt string
+	line   []rune
+	pos    int
+
+	// historyIndex is the history entry shown, History.Len() for the new
+	// line, whose contents are kept in pending while browsing.
+	historyIndex int
+	pending      []rune
+
+	// row is the row of the cursor below the prompt, which is not 0 only in
+	// entries recalled from multiline history.
+	row int
+}
+
+func (s *lineState) insert(runes []rune) {
+	line := make([]rune, 0, len(s.line)+len(runes))
+	line = append(line, s.line[:s.pos]...)
+	line = append(line, runes...)
+	line = append(line, s.line[s.pos:]...)
+	s.line = line
+	s.pos += len(runes)
+}
+
+func (s *lineState) deleteBackward() {
+	if s.pos == 0 {
+		return
+	}
+	s.line = append(s.line[:s.pos-1], s.line[s.pos:]...)
+	s.pos--
+}
+
+func (s *lineState) deleteForward() {
+	if s.pos >= len(s.line) {
+		return
+	}
+	s.line = append(s.line[:s.pos], s.line[s.pos+1:]...)
+}
+
+func (s *lineState) deleteWord() {
+	start := s.pos
+	for start > 0 && unicode.IsSpace(s.line[start-1]) {
+		start--
+	}
+	for start > 0 && !unicode.IsSpace(s.line[start-1]) {
+		start--
+	}
+	s.line = append(s.line[:start], s.line[s.pos:]...)
+	s.pos = start
+}
+
+func (s *lineState) set(line string) {
+	s.line = []rune(line)
+	s.pos = len(s.line)
+}
+
+func (e *Editor) historyMove(s *lineState, delta int) {
+	next := s.historyIndex + delta
+	if next < 0 || next > e.History.Len() {
+		return
+	}
+
+	if s.historyIndex == e.History.Len() {
+		s.pending = append([]rune{}, s.line...)
+	}
+	s.historyIndex = next
+
+	if next == e.History.Len() {
+		s.set(string(s.pending))
+	} else {
+		s.set(e.History.At(next))
+	}
+}
+
+func (e *Editor) complete(s *lineState) {
+	if e.Complete == nil {
+		return
+	}
+
+	start, candidates := e.Complete(s.line, s.pos)
+	if len(candidates) == 0 {
+		return
+	}
+
+	word := string(s.line[start:s.pos])
+	prefix := commonPrefix(candidates)
+	if len(candidates) == 1 {
+		prefix = candidates[0]
+	}
+
+	if len(prefix) > len(word) && strings.HasPrefix(prefix, word) {
+		s.insert([]rune(prefix[len(word):]))
+		e.lastWasTab = false
+		return
+	}
+
+	if e.lastWasTab {
+		e.moveToEnd(s)
+		e.newline()
+		fmt.Fprint(e.out, strings.Join(candidates, "  "))
+		e.newline()
+		s.row = 0
+	}
+	e.lastWasTab = true
+}
+
+// reverseSearch runs an incremental Ctrl-R search. It reports whether the
+// user accepted the match with Enter, in which case the line is complete.
+func (e *Editor) reverseSearch(s *lineState) (bool, error) {
+	query := []rune{}
+	match := -1
+	start := e.History.Len() - 1
+
+	for {
+		found := ""
+		if match >= 0 {
+			found = e.History.At(match)
+		}
+		// The match is shown on one line, but accepted with its newlines.
+		fmt.Fprintf(e.out, "\r\x1b[K(reverse-i-search)`%s': %s", string(query), strings.ReplaceAll(found, "\n", " "))
+
+		key, err := e.readKey()
+		if err != nil {
+			return false, err
+		}
+
+		switch key {
+		case keyCtrlR:
+			if match > 0 {
+				start = match - 1
+			}
+		case keyBackspace, keyDelete:
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+			}
+			start = e.History.Len() - 1
+		case keyCtrlG, keyCtrlC, keyEscape:
+			return false, nil
+		case keyEnter, keyLineFeed:
+			if match >= 0 {
+				s.set(found)
+			}
+			return true, nil
+		default:
+			if !unicode.IsPrint(rune(key)) {
+				// Any other key keeps the match and resumes normal editing.
+				if match >= 0 {
+					s.set(found)
+				}
+				return false, nil
+			}
+			query = append(query, rune(key))
+		}
+
+		if len(query) > 0 {
+			if next := e.History.Search(string(query), start); next >= 0 {
+				match = next
+			}
+		}
+	}
+}
+
+// readKey reads one key press, decoding escape sequences for arrow and
+// navigation keys.
+func (e *Editor) readKey() (rune, error) {
+	r, _, err := e.in.ReadRune()
+	if err != nil || r != keyEscape {
+		return r, err
+	}
+
+	next, _, err := e.in.ReadRune()
+	if err != nil {
+		return keyEscape, nil
+	}
+	if next != '[' && next != 'O' {
+		return keyUnknown, nil
+	}
+
+	params := ""
+	for {
+		c, _, err := e.in.ReadRune()
+		if err != nil {
+			return keyUnknown, nil
+		}
+		if c >= '0' && c <= '9' || c == ';' {
+			params += string(c)
+			continue
+		}
+
+		switch {
+		case c == 'A':
+			return keyUp, nil
+		case c == 'B':
+			return keyDown, nil
+		case c == 'C':
+			return keyRight, nil
+		case c == 'D':
+			return keyLeft, nil
+		case c == 'H':
+			return keyHome, nil
+		case c == 'F':
+			return keyEnd, nil
+		case c == '~' && (params == "1" || params == "7"):
+			return keyHome, nil
+		case c == '~' && (params == "4" || params == "8"):
+			return keyEnd, nil
+		case c == '~' && params == "3":
+			return keyForwardDelete, nil
+		default:
+			return keyUnknown, nil
+		}
+	}
+}
+
+// refresh redraws the prompt and line and places the cursor.
+// refresh redraws the prompt and the line. Lines with newlines, recalled
+// from history, take several rows, which are cleared from the first down.
+func (e *Editor) refresh(s *lineState) {
+	if s.row > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dA", s.row)
+	}
+	fmt.Fprintf(e.out, "\r\x1b[J%s%s", s.prompt, strings.ReplaceAll(string(s.line), "\n", "\r\n"))
+
+	before := s.line[:s.pos]
+	s.row = strings.Count(string(before), "\n")
+	if up := strings.Count(string(s.line), "\n") - s.row; up > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dA", up)
+	}
+
+	column := len([]rune(s.prompt))
+	for _, r := range before {
+		column++
+		if r == '\n' {
+			column = 0
+		}
+	}
+	fmt.Fprint(e.out, "\r")
+	if column > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dC", column)
+	}
+}
+
+// moveToEnd puts the cursor after the last row of a multiline line, so that
+// what follows is printed below it.
+func (e *Editor) moveToEnd(s *lineState) {
+	if s.pos != len(s.line) && strings.ContainsRune(string(s.line), '\n') {
+		s.pos = len(s.line)
+		e.refresh(s)
+	}
+}
+
+func (e *Editor) newline() {
+	fmt.Fprint(e.out, "\r\n")
+}
+
+func commonPrefix(words []string) string {
+	if len(words) == 0 {
+		return ""
+	}
+	prefix := words[0]
+	for _, word := range words[1:] {
+		for !strings.HasPrefix(word, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
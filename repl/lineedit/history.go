@@ -0,0 +1,143 @@
+package lineedit
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// MaxHistory is the number of entries kept in memory and on disk.
+const MaxHistory = 1000
+
+// History is the list of previously entered lines, oldest first, optionally
+// backed by a file so that it survives between sessions.
+type History struct {
+	entries []string
+	path    string
+}
+
+func NewHistory() *History {
+	return &History{entries: []string{}}
+}
+
+// LoadHistory reads the history stored at path. A missing file yields an
+// empty history that will be created on the first Add.
+func LoadHistory(path string) (*History, error) {
+	h := NewHistory()
+	h.path = path
+
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return h, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		h.entries = append(h.entries, unescapeEntry(scanner.Text()))
+	}
+	h.trim()
+
+	return h, scanner.Err()
+}
+
+// Add appends line to the history, skipping blank lines and immediate
+// repeats, and persists it when the history is backed by a file.
+func (h *History) Add(line string) error {
+	if strings.TrimSpace(line) == "" {
+		return nil
+	}
+	if len(h.entries) > 0 && h.entries[len(h.entries)-1] == line {
+		return nil
+	}
+
+	h.entries = append(h.entries, line)
+	if !h.trim() {
+		return h.appendToFile(line)
+	}
+	return h.rewriteFile()
+}
+
+func (h *History) Len() int {
+	return len(h.entries)
+}
+
+// At returns the entry at index, where 0 is the oldest.
+func (h *History) At(index int) string {
+	return h.entries[index]
+}
+
+// Search looks backwards from index start for an entry containing query and
+// returns its index, or -1 if there is none.
+func (h *History) Search(query string, start int) int {
+	if start >= len(h.entries) {
+		start = len(h.entries) - 1
+	}
+	for i := start; i >= 0; i-- {
+		if strings.Contains(h.entries[i], query) {
+			return i
+		}
+	}
+	return -1
+}
+
+// trim drops the oldest entries beyond MaxHistory and reports whether it did.
+func (h *History) trim() bool {
+	if len(h.entries) <= MaxHistory {
+		return false
+	}
+	h.entries = h.entries[len(h.entries)-MaxHistory:]
+	return true
+}
+
+func (h *History) appendToFile(line string) error {
+	if h.path == "" {
+		return nil
+	}
+
+	file, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(escapeEntry(line) + "\n")
+	return err
+}
+
+func (h *History) rewriteFile() error {
+	if h.path == "" {
+		return nil
+	}
+
+	var out strings.Builder
+	for _, entry := range h.entries {
+		out.WriteString(escapeEntry(entry) + "\n")
+	}
+	return os.WriteFile(h.path, []byte(out.String()), 0o600)
+}
+
+// escapeEntry keeps multiline entries on a single line of the history file.
+func escapeEntry(entry string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(entry)
+}
+
+func unescapeEntry(line string) string {
+	var out strings.Builder
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' && i+1 < len(line) {
+			i++
+			if line[i] == 'n' {
+				out.WriteByte('\n')
+				continue
+			}
+		}
+		out.WriteByte(line[i])
+	}
+	return out.String()
+}
@@ -0,0 +1,145 @@
+package lineedit
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadLine(t *testing.T) {
+	tests := []struct {
+		keys     string
+		history  []string
+		expected string
+	}{
+		{"abc\r", nil, "abc"},
+		{"abd\x7fc\r", nil, "abc"},
+		{"ac\x1b[Db\r", nil, "abc"},
+		{"bc\x01a\x05d\r", nil, "abcd"},
+		{"abcdef\x1b[D\x1b[D\x0b\r", nil, "abcd"},
+		{"xyz\x1b[D\x15abc\r", nil, "abcz"},
+		{"let x = 1\x17\x17\r", nil, "let x "},
+		{"ab\x1b[H\x1b[3~\r", nil, "b"},
+		{"\x1b[A\r", []string{"first", "second"}, "second"},
+		{"\x1b[A\x1b[A\r", []string{"first", "second"}, "first"},
+		{"new\x1b[A\x1b[B\r", []string{"first"}, "new"},
+		{"\x1b[A\r", []string{"let x = 1; // one\nx"}, "let x = 1; // one\nx"},
+		{"\x1b[A\x1b[D\x1b[D\x7f!\r", []string{"a\nbc"}, "a!bc"},
+		{"\x12one\r", []string{"// one\n1"}, "// one\n1"},
+		{"\x12fir\r", []string{"first", "second", "third"}, "first"},
+		{"\x12ir\x12\r", []string{"first", "third"}, "first"},
+		{"\x12sec\x07\r", []string{"second"}, ""},
+		{"\x12sec\x05!\r", []string{"second"}, "second!"},
+		{"partial", nil, "partial"},
+	}
+
+	for _, tt := range tests {
+		editor := New(strings.NewReader(tt.keys), io.Discard)
+		for _, entry := range tt.history {
+			editor.History.Add(entry)
+		}
+
+		line, err := editor.ReadLine("> ")
+		if err != nil {
+			t.Fatalf("keys %q: unexpected error %s", tt.keys, err)
+		}
+		if line != tt.expected {
+			t.Errorf("keys %q: wrong line. want=%q, got=%q", tt.keys, tt.expected, line)
+		}
+	}
+}
+
+func TestReadLineControl(t *testing.T) {
+	editor := New(strings.NewReader("abc\x03"), io.Discard)
+	if _, err := editor.ReadLine("> "); !errors.Is(err, ErrInterrupted) {
+		t.Errorf("Ctrl-C: wrong error. want=%s, got=%v", ErrInterrupted, err)
+	}
+
+	editor = New(strings.NewReader("\x04"), io.Discard)
+	if _, err := editor.ReadLine("> "); err != io.EOF {
+		t.Errorf("Ctrl-D: wrong error. want=%s, got=%v", io.EOF, err)
+	}
+}
+
+func TestComplete(t *testing.T) {
+	complete := func(line []rune, pos int) (int, []string) {
+		start := pos
+		for start > 0 && line[start-1] != ' ' {
+			start--
+		}
+		candidates := []string{}
+		for _, word := range []string{"len", "let", "first"} {
+			if strings.HasPrefix(word, string(line[start:pos])) {
+				candidates = append(candidates, word)
+			}
+		}
+		return start, candidates
+	}
+
+	tests := []struct {
+		keys     string
+		expected string
+		listed   string
+	}{
+		{"fi\t\r", "first", ""},
+		{"l\t\r", "le", ""},
+		{"le\t\t\r", "le", "len  let"},
+		{"x\t\r", "x", ""},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		editor := New(strings.NewReader(tt.keys), &out)
+		editor.Complete = complete
+
+		line, err := editor.ReadLine("> ")
+		if err != nil {
+			t.Fatalf("keys %q: unexpected error %s", tt.keys, err)
+		}
+		if line != tt.expected {
+			t.Errorf("keys %q: wrong line. want=%q, got=%q", tt.keys, tt.expected, line)
+		}
+		if tt.listed != "" && !strings.Contains(out.String(), tt.listed) {
+			t.Errorf("keys %q: candidates %q not listed in %q", tt.keys, tt.listed, out.String())
+		}
+	}
+}
+
+func TestHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+
+	history, err := LoadHistory(path)
+	if err != nil {
+		t.Fatalf("missing history file: %s", err)
+	}
+	for _, entry := range []string{"one", "one", "  ", "two\nlines", `back\slash`} {
+		if err := history.Add(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	loaded, err := LoadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"one", "two\nlines", `back\slash`}
+	if loaded.Len() != len(expected) {
+		t.Fatalf("wrong number of entries. want=%d, got=%d", len(expected), loaded.Len())
+	}
+	for i, entry := range expected {
+		if loaded.At(i) != entry {
+			t.Errorf("wrong entry %d. want=%q, got=%q", i, entry, loaded.At(i))
+		}
+	}
+
+	if index := loaded.Search("o", 2); index != 1 {
+		t.Errorf("wrong search result. want=%d, got=%d", 1, index)
+	}
+	if index := loaded.Search("missing", 2); index != -1 {
+		t.Errorf("wrong search result. want=%d, got=%d", -1, index)
+	}
+}
@@ -1,70 +1,207 @@
 package repl
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
 	"io"
+	"strings"
+	"time"
 
-	"github.com/mislavperi/adl-lang/compiler"
+	"github.com/mislavperi/adl-lang/ast"
+	"github.com/mislavperi/adl-lang/engine"
 	"github.com/mislavperi/adl-lang/lexer"
-	"github.com/mislavperi/adl-lang/representation"
 	"github.com/mislavperi/adl-lang/parser"
-	symboltable "github.com/mislavperi/adl-lang/symbol_table"
-	"github.com/mislavperi/adl-lang/vm"
+	"github.com/mislavperi/adl-lang/repl/lineedit"
+	"github.com/mislavperi/adl-lang/representation"
+	"github.com/mislavperi/adl-lang/token"
 )
 
 const PROMPT = ">>"
 
-func Start(in io.Reader, out io.Writer) {
-	scanner := bufio.NewScanner(in)
+// CONTINUATION_PROMPT is shown while an incomplete entry spans several lines.
+const CONTINUATION_PROMPT = ".."
+
+// errQuit is returned by meta-commands that end the session. Programs that
+// call exit end it with the *representation.Exit they stopped with.
+var errQuit = errors.New("quit")
+
+// Session holds the state that survives between REPL entries.
+type Session struct {
+	out    io.Writer
+	host   *representation.Host
+	engine *engine.VMEngine
+
+	// timing reports how long each entry took to run.
+	timing bool
+}
+
+func NewSession(out io.Writer, host *representation.Host) *Session {
+	if host == nil {
+		host = representation.NewHost()
+	}
+	if host.Stdout == nil {
+		host.Stdout = out
+	}
+	return &Session{out: out, host: host, engine: engine.NewVM(host)}
+}
+
+func Start(in io.Reader, out io.Writer) int {
+	return StartWithHost(in, out, representation.NewHost())
+}
 
-	constants := []representation.Representation{}
-	globals := make([]representation.Representation, vm.GlobalsSize)
-	symbolTable := symboltable.NewSymbolTable()
+// StartWithHost runs the REPL with builtins running against host. When in is
+// a terminal, lines can be edited, recalled from history and tab-completed.
+// It returns the status the session ended with, which is the code passed to
+// exit or 0. A host without Stdin reads the lines typed after an entry.
+func StartWithHost(in io.Reader, out io.Writer, host *representation.Host) int {
+	session := NewSession(out, host)
+	reader := newLineReader(in, out, session)
 
-	for index, builtin := range representation.Builtins {
-		symbolTable.DefineBuiltin(index, builtin.Name)
+	tail := &lineTail{w: session.host.Stdout}
+	if session.host.Stdin == nil {
+		session.host.Stdout = tail
+		session.host.Stdin = &programInput{reader: reader, output: tail}
 	}
 
+	var entry strings.Builder
 	for {
-		fmt.Fprint(out, PROMPT)
-		scanned := scanner.Scan()
-		if !scanned {
-			return
+		prompt := PROMPT
+		if entry.Len() != 0 {
+			prompt = CONTINUATION_PROMPT
 		}
 
-		line := scanner.Text()
-		l := lexer.New(line)
-		p := parser.New(l)
-
-		program := p.ParseProgram()
-		if len(p.Errors()) != 0 {
-			printParserErrors(out, p.Errors())
+		line, err := reader.ReadLine(prompt)
+		if errors.Is(err, lineedit.ErrInterrupted) {
+			// Ctrl-C abandons the entry being typed, not the session.
+			entry.Reset()
 			continue
 		}
-
-		compiler := compiler.NewWithState(symbolTable, constants)
-		err := compiler.Compile(program)
 		if err != nil {
-			fmt.Fprint(out, "Complidation failed:\n", err)
+			return 0
+		}
+
+		if entry.Len() == 0 && strings.HasPrefix(strings.TrimSpace(line), ":") {
+			reader.AddHistory(strings.TrimSpace(line))
+			if code, quit := exitCode(session.Command(strings.TrimSpace(line))); quit {
+				return code
+			}
 			continue
 		}
 
-		code := compiler.Bytecode()
-		constants = code.Constants
+		entry.WriteString(line)
+		entry.WriteString("\n")
 
-		machine := vm.NewWithGlobalStore(code, globals)
-		err = machine.Run()
-		if err != nil {
-			fmt.Fprint(out, "Execution of bytecode failed:\n", err)
+		// An empty line submits an incomplete entry so the parser can report
+		// what is wrong with it.
+		if IsIncomplete(entry.String()) && strings.TrimSpace(line) != "" {
 			continue
 		}
 
-		stackTop := machine.LastPoppedStackElem()
-		io.WriteString(out, stackTop.Inspect())
-		io.WriteString(out, "\n")
+		source := entry.String()
+		entry.Reset()
+		if strings.TrimSpace(source) == "" {
+			continue
+		}
+
+		// Multiline entries are recalled with their newlines, which matter
+		// when a line ends in a comment.
+		reader.AddHistory(strings.TrimSpace(source))
+		tail.partial = ""
+		if code, quit := exitCode(session.Eval(source)); quit {
+			return code
+		}
+	}
+}
+
+// exitCode tells whether an error returned by a command or an entry ends the
+// session, and with which status.
+func exitCode(err error) (int, bool) {
+	var exit *representation.Exit
+	if errors.As(err, &exit) {
+		return exit.Code, true
+	}
+	return 0, errors.Is(err, errQuit)
+}
+
+// Eval runs source in the session and prints its value or the errors it caused.
+func (s *Session) Eval(source string) error {
+	program, ok := s.parse(source)
+	if !ok {
+		return nil
+	}
+
+	start := time.Now()
+	result, err := s.engine.Run(program)
+	elapsed := time.Since(start)
+
+	if _, ok := err.(*representation.Exit); ok {
+		return err
+	}
+	if err != nil {
+		fmt.Fprintf(s.out, "Execution failed:\n%s\n", err)
+		return nil
+	}
+
+	if result != nil {
+		io.WriteString(s.out, result.Inspect())
+		io.WriteString(s.out, "\n")
+	}
+	if s.timing {
+		fmt.Fprintf(s.out, "(%s)\n", elapsed)
+	}
+
+	return nil
+}
 
+func (s *Session) parse(source string) (*ast.Program, bool) {
+	p := parser.New(lexer.New(source))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		printParserErrors(s.out, p.Errors())
+		return nil, false
 	}
+	return program, true
+}
+
+// continuationTokens are the tokens that cannot end an entry, because the
+// expression they start is still missing its right-hand side.
+var continuationTokens = map[token.TokenType]bool{
+	token.ASSIGN:   true,
+	token.PLUS:     true,
+	token.MINUS:    true,
+	token.BANG:     true,
+	token.ASTERISK: true,
+	token.SLASH:    true,
+	token.LT:       true,
+	token.GT:       true,
+	token.EQ:       true,
+	token.NOT_EQ:   true,
+	token.COMMA:    true,
+	token.COLON:    true,
+	token.FUNCTION: true,
+	token.LET:      true,
+	token.IF:       true,
+	token.ELSE:     true,
+	token.RETURN:   true,
+}
+
+// IsIncomplete reports whether source needs more lines before it can be
+// parsed: it has unclosed brackets or strings, or ends in an operator.
+func IsIncomplete(source string) bool {
+	l := lexer.New(source)
+	depth := 0
+	last := token.Token{Type: token.EOF}
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		switch tok.Type {
+		case token.LPAREN, token.LBRACE, token.LBRACKET:
+			depth++
+		case token.RPAREN, token.RBRACE, token.RBRACKET:
+			depth--
+		}
+		last = tok
+	}
+
+	return depth > 0 || l.Unterminated() || continuationTokens[last.Type]
 }
 
 func printParserErrors(out io.Writer, errors []string) {
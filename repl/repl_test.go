@@ -0,0 +1,142 @@
+package repl
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIsIncomplete(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"1 + 2", false},
+		{"1 +", true},
+		{"let x =", true},
+		{"let f = fn(a) {", true},
+		{"let f = fn(a) { a }", false},
+		{"add(1,", true},
+		{"[1, 2", true},
+		{`"unterminated`, true},
+		{`"done"`, false},
+		{`let q = "a\"b";`, false},
+		{`"a\"`, true},
+		{`"back\\"`, false},
+		{`1 // don't "quote`, false},
+		{"}", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsIncomplete(tt.input); got != tt.expected {
+			t.Errorf("IsIncomplete(%q) wrong. want=%t, got=%t", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestStart(t *testing.T) {
+	tests := []struct {
+		input    string
+		contains []string
+		excludes []string
+	}{
+		{
+			input:    "let add = fn(a,\nb) {\na + b\n};\nadd(1, 2)\n",
+			contains: []string{"3\n"},
+		},
+		{
+			input:    "let x = 1;\n:globals\n:reset\n:globals\n",
+			contains: []string{"x = 1\n", "session reset\n>>>>"},
+		},
+		{
+			input:    ":dis fn(a) { a }\n",
+			contains: []string{"OpClosure", "OpGetLocal 0", "OpReturnValue"},
+		},
+		{
+			input:    ":ast 1 + 2\n",
+			contains: []string{"InfixExpression +\n", "IntegerLiteral 2\n"},
+		},
+		{
+			input:    "1 +\n\n",
+			contains: []string{"parser errors"},
+		},
+		{
+			input:    ":quit\n1 + 1\n",
+			excludes: []string{"2\n"},
+		},
+		{
+			input:    "exit()\n1 + 1\n",
+			excludes: []string{"2\n"},
+		},
+		{
+			input:    "print(\"a\"); out(\"b\")\n",
+			contains: []string{">>ab\nnull\n"},
+		},
+		{
+			input:    "let name = input(\"Name: \")\nAda\nname\n",
+			contains: []string{">>Name: >>Ada\n"},
+		},
+		{
+			input:    ":nope\n",
+			contains: []string{"unknown command :nope"},
+		},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		Start(strings.NewReader(tt.input), &out)
+
+		for _, want := range tt.contains {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("output for %q does not contain %q:\n%s", tt.input, want, out.String())
+			}
+		}
+		for _, unwanted := range tt.excludes {
+			if strings.Contains(out.String(), unwanted) {
+				t.Errorf("output for %q contains %q:\n%s", tt.input, unwanted, out.String())
+			}
+		}
+	}
+}
+
+func TestComplete(t *testing.T) {
+	session := NewSession(&bytes.Buffer{}, nil)
+	session.Eval("let lenient = 1;")
+
+	tests := []struct {
+		line     string
+		expected []string
+	}{
+		{"le", []string{"len", "lenient", "let"}},
+		{"1 + leni", []string{"lenient"}},
+		{"ret", []string{"return"}},
+		{"pu", []string{"push"}},
+		{":gl", []string{"globals"}},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		line := []rune(tt.line)
+		_, got := session.Complete(line, len(line))
+		if strings.Join(got, " ") != strings.Join(tt.expected, " ") {
+			t.Errorf("Complete(%q) wrong. want=%v, got=%v", tt.line, tt.expected, got)
+		}
+	}
+}
+
+func TestSaveAndRestore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session")
+
+	var out bytes.Buffer
+	Start(strings.NewReader("let add = fn(a) { fn(b) { a + b } };\nlet inc = add(1);\n:save "+path+"\n"), &out)
+	if !strings.Contains(out.String(), "session saved") {
+		t.Fatalf("session not saved:\n%s", out.String())
+	}
+
+	out.Reset()
+	Start(strings.NewReader(":restore "+path+"\ninc(41)\n"), &out)
+	if !strings.Contains(out.String(), "42\n") {
+		t.Errorf("restored session did not keep its globals:\n%s", out.String())
+	}
+}
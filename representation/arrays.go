@@ -0,0 +1,322 @@
+package representation
+
+import (
+	"math"
+	"sort"
+)
+
+func builtinMap(host *Host, args ...Representation) Representation {
+	arr, fn, err := arrayAndFunction("map", args)
+	if err != nil {
+		return err
+	}
+
+	elements := make([]Representation, len(arr.Elements))
+	for i, element := range arr.Elements {
+		result := host.call(fn, element)
+		if Stops(result) {
+			return result
+		}
+		elements[i] = result
+	}
+	return &Array{Elements: elements}
+}
+
+func builtinFilter(host *Host, args ...Representation) Representation {
+	arr, fn, err := arrayAndFunction("filter", args)
+	if err != nil {
+		return err
+	}
+
+	elements := []Representation{}
+	for _, element := range arr.Elements {
+		result := host.call(fn, element)
+		if Stops(result) {
+			return result
+		}
+		if truthy(result) {
+			elements = append(elements, element)
+		}
+	}
+	return &Array{Elements: elements}
+}
+
+func builtinReduce(host *Host, args ...Representation) Representation {
+	if err := checkArity(args, 2, 3); err != nil {
+		return err
+	}
+	arr, fn, err := arrayAndFunction("reduce", args[:2])
+	if err != nil {
+		return err
+	}
+
+	elements := arr.Elements
+	var accumulator Representation
+	if len(args) == 3 {
+		accumulator = args[2]
+	} else if len(elements) > 0 {
+		accumulator, elements = elements[0], elements[1:]
+	} else {
+		return newError("`reduce` of an empty array needs an initial value")
+	}
+
+	for _, element := range elements {
+		accumulator = host.call(fn, accumulator, element)
+		if Stops(accumulator) {
+			return accumulator
+		}
+	}
+	return accumulator
+}
+
+func builtinSort(host *Host, args ...Representation) Representation {
+	if err := checkArity(args, 1, 2); err != nil {
+		return err
+	}
+	arr, err := arrayArgument("sort", args, 0)
+	if err != nil {
+		return err
+	}
+
+	elements := make([]Representation, len(arr.Elements))
+	copy(elements, arr.Elements)
+
+	// The first error stops the comparisons that follow from doing any work.
+	var stopped Representation
+	less := func(a Representation, b Representation) bool {
+		order, err := Compare(a, b)
+		if err != nil {
+			stopped = err
+		}
+		return order < 0
+	}
+
+	if len(args) == 2 {
+		fn, err := functionArgument("sort", args, 1)
+		if err != nil {
+			return err
+		}
+		less = func(a Representation, b Representation) bool {
+			result := host.call(fn, a, b)
+			if Stops(result) {
+				stopped = result
+				return false
+			}
+			return truthy(result)
+		}
+	}
+
+	sort.SliceStable(elements, func(i int, j int) bool {
+		if stopped != nil {
+			return false
+		}
+		return less(elements[i], elements[j])
+	})
+
+	if stopped != nil {
+		return stopped
+	}
+	return &Array{Elements: elements}
+}
+
+func builtinReverse(host *Host, args ...Representation) Representation {
+	if err := checkArity(args, 1, 1); err != nil {
+		return err
+	}
+	arr, err := arrayArgument("reverse", args, 0)
+	if err != nil {
+		return err
+	}
+
+	length := len(arr.Elements)
+	elements := make([]Representation, length)
+	for i, element := range arr.Elements {
+		elements[length-1-i] = element
+	}
+	return &Array{Elements: elements}
+}
+
+// arrayIndexOf returns the index of the first element equal to value, or -1.
+func arrayIndexOf(arr *Array, value Representation) int {
+	for i, element := range arr.Elements {
+		if Equal(element, value) {
+			return i
+		}
+	}
+	return -1
+}
+
+func builtinZip(host *Host, args ...Representation) Representation {
+	if err := checkArity(args, 2, 2); err != nil {
+		return err
+	}
+	left, err := arrayArgument("zip", args, 0)
+	if err != nil {
+		return err
+	}
+	right, err := arrayArgument("zip", args, 1)
+	if err != nil {
+		return err
+	}
+
+	length := len(left.Elements)
+	if len(right.Elements) < length {
+		length = len(right.Elements)
+	}
+
+	pairs := make([]Representation, length)
+	for i := range pairs {
+		pairs[i] = &Array{Elements: []Representation{left.Elements[i], right.Elements[i]}}
+	}
+	return &Array{Elements: pairs}
+}
+
+func builtinFlatten(host *Host, args ...Representation) Representation {
+	if err := checkArity(args, 1, 2); err != nil {
+		return err
+	}
+	arr, err := arrayArgument("flatten", args, 0)
+	if err != nil {
+		return err
+	}
+
+	depth := int64(1)
+	if len(args) == 2 {
+		n, ok := args[1].(*Integer)
+		if !ok {
+			return newError("argument 2 to `flatten` must be an integer, got %s", args[1].Type())
+		}
+		depth = n.Value
+	}
+
+	return &Array{Elements: flatten(arr.Elements, depth)}
+}
+
+func flatten(elements []Representation, depth int64) []Representation {
+	flat := []Representation{}
+	for _, element := range elements {
+		if inner, ok := element.(*Array); ok && depth > 0 {
+			flat = append(flat, flatten(inner.Elements, depth-1)...)
+		} else {
+			flat = append(flat, element)
+		}
+	}
+	return flat
+}
+
+func builtinRange(host *Host, args ...Representation) Representation {
+	if err := checkArity(args, 1, 3); err != nil {
+		return err
+	}
+
+	bounds := make([]int64, len(args))
+	for i, arg := range args {
+		n, ok := arg.(*Integer)
+		if !ok {
+			return newError("arguments to `range` must be integers, got %s", arg.Type())
+		}
+		bounds[i] = n.Value
+	}
+
+	start, end, step := int64(0), bounds[0], int64(1)
+	if len(bounds) > 1 {
+		start, end = bounds[0], bounds[1]
+	}
+	if len(bounds) > 2 {
+		step = bounds[2]
+	}
+	if step == 0 {
+		return newError("`range` step must not be zero")
+	}
+
+	elements := []Representation{}
+	for i := start; (step > 0 && i < end) || (step < 0 && i > end); i += step {
+		elements = append(elements, &Integer{Value: i})
+
+		// The next element would overflow, so it is past the end.
+		if (step > 0 && i > math.MaxInt64-step) || (step < 0 && i < math.MinInt64-step) {
+			break
+		}
+	}
+	return &Array{Elements: elements}
+}
+
+func builtinSlice(host *Host, args ...Representation) Representation {
+	if err := checkArity(args, 2, 3); err != nil {
+		return err
+	}
+	arr, err := arrayArgument("slice", args, 0)
+	if err != nil {
+		return err
+	}
+
+	bounds := []int64{0, int64(len(arr.Elements))}
+	for i, arg := range args[1:] {
+		n, ok := arg.(*Integer)
+		if !ok {
+			return newError("argument %d to `slice` must be an integer, got %s", i+2, arg.Type())
+		}
+		bounds[i] = n.Value
+	}
+
+	start, end := bounds[0], bounds[1]
+	if start < 0 || end > int64(len(arr.Elements)) || start > end {
+		return newError("slice bounds out of range: [%d:%d] with length %d", start, end, len(arr.Elements))
+	}
+
+	elements := make([]Representation, end-start)
+	copy(elements, arr.Elements[start:end])
+	return &Array{Elements: elements}
+}
+
+// arrayAndFunction checks the arguments of builtins called as f(arr, fn).
+func arrayAndFunction(name string, args []Representation) (*Array, Representation, *Error) {
+	if err := checkArity(args, 2, 2); err != nil {
+		return nil, nil, err
+	}
+	arr, err := arrayArgument(name, args, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	fn, err := functionArgument(name, args, 1)
+	if err != nil {
+		return nil, nil, err
+	}
+	return arr, fn, nil
+}
+
+// arrayArgument returns the argument of a builtin at index, which must be an
+// array.
+func arrayArgument(name string, args []Representation, index int) (*Array, *Error) {
+	arr, ok := args[index].(*Array)
+	if !ok {
+		if len(args) == 1 {
+			return nil, newError("argument to `%s` must be an array, got %s", name, args[index].Type())
+		}
+		return nil, newError("argument %d to `%s` must be an array, got %s", index+1, name, args[index].Type())
+	}
+	return arr, nil
+}
+
+// functionArgument returns the argument of a builtin at index, which must be
+// callable.
+func functionArgument(name string, args []Representation, index int) (Representation, *Error) {
+	switch args[index].(type) {
+	case *Closure, *Function, *Builtin:
+		return args[index], nil
+	default:
+		return nil, newError("argument %d to `%s` must be a function, got %s", index+1, name, args[index].Type())
+	}
+}
+
+// truthy reports whether a value counts as true in a condition.
+func truthy(value Representation) bool {
+	switch value := value.(type) {
+	case *Boolean:
+		return value.Value
+	case *Null:
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,85 @@
+package representation
+
+import (
+	"fmt"
+	"strings"
+)
+
+func builtinAssert(host *Host, args ...Representation) Representation {
+	if len(args) < 1 || len(args) > 2 {
+		return newError("wrong number of arguments, got=%d, want=1 or 2", len(args))
+	}
+
+	if truthy(args[0]) {
+		return nil
+	}
+	return &Failure{Message: failureMessage("assertion failed", args[1:])}
+}
+
+func builtinAssertEq(host *Host, args ...Representation) Representation {
+	if len(args) < 2 || len(args) > 3 {
+		return newError("wrong number of arguments, got=%d, want=2 or 3", len(args))
+	}
+
+	got, want := args[0], args[1]
+	if got.Type() == want.Type() && got.Inspect() == want.Inspect() {
+		return nil
+	}
+
+	message := failureMessage("values are not equal", args[2:])
+	return &Failure{Message: message + "\n" + diff(want, got)}
+}
+
+func builtinTest(host *Host, args ...Representation) Representation {
+	if len(args) != 2 {
+		return newError("wrong number of arguments, got=%d, want=2", len(args))
+	}
+	name, ok := args[0].(*String)
+	if !ok {
+		return newError("first argument to `test` must be a string, got %s", args[0].Type())
+	}
+	fn, err := functionArgument("test", args, 1)
+	if err != nil {
+		return err
+	}
+
+	var result Representation
+	if host.Test != nil {
+		result = host.Test(name.Value, fn)
+	} else {
+		result = host.call(fn)
+	}
+
+	if Stops(result) {
+		return result
+	}
+	return nil
+}
+
+// failureMessage uses the optional message argument of an assertion, or
+// fallback when there is none.
+func failureMessage(fallback string, message []Representation) string {
+	if len(message) == 0 {
+		return fallback
+	}
+	if s, ok := message[0].(*String); ok {
+		return s.Value
+	}
+	return message[0].Inspect()
+}
+
+// diff shows the inspected values one above the other, marking the first
+// position at which they differ.
+func diff(want Representation, got Representation) string {
+	w, g := want.Inspect(), got.Inspect()
+	if w == g {
+		// Same text, different types, such as 1 and "1".
+		return fmt.Sprintf("want: %s (%s)\n got: %s (%s)", w, want.Type(), g, got.Type())
+	}
+
+	at := 0
+	for at < len(w) && at < len(g) && w[at] == g[at] {
+		at++
+	}
+	return fmt.Sprintf("want: %s\n got: %s\n      %s^", w, g, strings.Repeat(" ", at))
+}
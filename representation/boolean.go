@@ -15,3 +15,18 @@ func (b *Boolean) HashKey() HashKey {
 	}
 	return HashKey{Type: b.Type(), Value: value}
 }
+
+// TRUE and FALSE are the only boolean values, so that engines can compare
+// booleans by identity.
+var (
+	TRUE  = &Boolean{Value: true}
+	FALSE = &Boolean{Value: false}
+)
+
+// NativeBool returns the boolean value for b.
+func NativeBool(b bool) *Boolean {
+	if b {
+		return TRUE
+	}
+	return FALSE
+}
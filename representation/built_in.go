@@ -1,9 +1,15 @@
 package representation
 
-type BuiltinFunction func(args ...Representation) Representation
+// BuiltinFunction implements a builtin. A returned value that also implements
+// error, such as *Exit, stops the engine running the program.
+type BuiltinFunction func(host *Host, args ...Representation) Representation
 
 type Builtin struct {
 	Fn BuiltinFunction
+
+	// Value makes the builtin a constant, such as PI: its name stands for
+	// Value rather than for a function.
+	Value Representation
 }
 
 func (b *Builtin) Type() RepresentationType { return BUILTIN_REPR }
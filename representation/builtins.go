@@ -1,6 +1,10 @@
 package representation
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+	"unicode/utf8"
+)
 
 var Builtins = []struct {
 	Name    string
@@ -8,7 +12,7 @@ var Builtins = []struct {
 }{
 	{
 		"len",
-		&Builtin{Fn: func(args ...Representation) Representation {
+		&Builtin{Fn: func(host *Host, args ...Representation) Representation {
 			if len(args) != 1 {
 				return newError("wrong number of arguments. got=%d, want=1", len(args))
 			}
@@ -17,30 +21,21 @@ var Builtins = []struct {
 			case *Array:
 				return &Integer{Value: int64(len(arg.Elements))}
 			case *String:
-				return &Integer{Value: int64(len(arg.Value))}
+				return &Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
+			case *Hash:
+				return &Integer{Value: int64(len(arg.Pairs))}
 			default:
 				return newError("argument to `len` not supported, got %s", args[0].Type())
 			}
 		},
 		},
 	},
-	{
-		"out",
-		&Builtin{
-
-			Fn: func(args ...Representation) Representation {
-				for _, arg := range args {
-					fmt.Println(arg.Inspect())
-				}
-				return nil
-			},
-		},
-	},
+	{"out", &Builtin{Fn: builtinOut}},
 	{
 		"first",
 		&Builtin{
 
-			Fn: func(args ...Representation) Representation {
+			Fn: func(host *Host, args ...Representation) Representation {
 				if len(args) != 1 {
 					return newError("wrong number of arguments, got=%d, want=1", len(args))
 				}
@@ -61,7 +56,7 @@ var Builtins = []struct {
 		"last",
 		&Builtin{
 
-			Fn: func(args ...Representation) Representation {
+			Fn: func(host *Host, args ...Representation) Representation {
 				if len(args) != 1 {
 					return newError("wrong number of arguments, got=%d, want=1", len(args))
 				}
@@ -82,7 +77,7 @@ var Builtins = []struct {
 		"rest",
 		&Builtin{
 
-			Fn: func(args ...Representation) Representation {
+			Fn: func(host *Host, args ...Representation) Representation {
 				if len(args) != 1 {
 					return newError("wrong number of arguments, got=%d, want=1", len(args))
 				}
@@ -107,7 +102,7 @@ var Builtins = []struct {
 		"push",
 		&Builtin{
 
-			Fn: func(args ...Representation) Representation {
+			Fn: func(host *Host, args ...Representation) Representation {
 				if len(args) != 2 {
 					return newError("wrong number of arguments, got=%d, want=2", len(args))
 				}
@@ -126,6 +121,171 @@ var Builtins = []struct {
 			},
 		},
 	},
+	{
+		"args",
+		&Builtin{
+
+			Fn: func(host *Host, args ...Representation) Representation {
+				if len(args) != 0 {
+					return newError("wrong number of arguments, got=%d, want=0", len(args))
+				}
+
+				elements := make([]Representation, len(host.Args))
+				for i, arg := range host.Args {
+					elements[i] = &String{Value: arg}
+				}
+
+				return &Array{Elements: elements}
+			},
+		},
+	},
+	{
+		"env",
+		&Builtin{
+
+			Fn: func(host *Host, args ...Representation) Representation {
+				if len(args) != 1 {
+					return newError("wrong number of arguments, got=%d, want=1", len(args))
+				}
+				name, ok := args[0].(*String)
+				if !ok {
+					return newError("argument to `env` must be a string, got %s", args[0].Type())
+				}
+
+				if host.LookupEnv == nil {
+					return nil
+				}
+
+				value, ok := host.LookupEnv(name.Value)
+				if !ok {
+					return nil
+				}
+
+				return &String{Value: value}
+			},
+		},
+	},
+	{
+		"exit",
+		&Builtin{
+
+			Fn: func(host *Host, args ...Representation) Representation {
+				if len(args) > 1 {
+					return newError("wrong number of arguments, got=%d, want=0 or 1", len(args))
+				}
+				if len(args) == 0 {
+					return &Exit{Code: 0}
+				}
+
+				code, ok := args[0].(*Integer)
+				if !ok {
+					return newError("argument to `exit` must be an integer, got %s", args[0].Type())
+				}
+
+				return &Exit{Code: int(code.Value)}
+			},
+		},
+	},
+	{"assert", &Builtin{Fn: builtinAssert}},
+	{"assert_eq", &Builtin{Fn: builtinAssertEq}},
+	{"test", &Builtin{Fn: builtinTest}},
+	{"split", &Builtin{Fn: builtinSplit}},
+	{"join", &Builtin{Fn: builtinJoin}},
+	{"trim", &Builtin{Fn: builtinTrim}},
+	{"upper", &Builtin{Fn: builtinUpper}},
+	{"lower", &Builtin{Fn: builtinLower}},
+	{"contains", &Builtin{Fn: builtinContains}},
+	{"starts_with", &Builtin{Fn: builtinStartsWith}},
+	{"ends_with", &Builtin{Fn: builtinEndsWith}},
+	{"replace", &Builtin{Fn: builtinReplace}},
+	{"index_of", &Builtin{Fn: builtinIndexOf}},
+	{"substring", &Builtin{Fn: builtinSubstring}},
+	{"repeat", &Builtin{Fn: builtinRepeat}},
+	{"chars", &Builtin{Fn: builtinChars}},
+	{"map", &Builtin{Fn: builtinMap}},
+	{"filter", &Builtin{Fn: builtinFilter}},
+	{"reduce", &Builtin{Fn: builtinReduce}},
+	{"sort", &Builtin{Fn: builtinSort}},
+	{"reverse", &Builtin{Fn: builtinReverse}},
+	{"zip", &Builtin{Fn: builtinZip}},
+	{"flatten", &Builtin{Fn: builtinFlatten}},
+	{"range", &Builtin{Fn: builtinRange}},
+	{"slice", &Builtin{Fn: builtinSlice}},
+	{"keys", &Builtin{Fn: builtinKeys}},
+	{"values", &Builtin{Fn: builtinValues}},
+	{"entries", &Builtin{Fn: builtinEntries}},
+	{"has_key", &Builtin{Fn: builtinHasKey}},
+	{"delete", &Builtin{Fn: builtinDelete}},
+	{"merge", &Builtin{Fn: builtinMerge}},
+	{"json_parse", &Builtin{Fn: builtinJSONParse}},
+	{"json_stringify", &Builtin{Fn: builtinJSONStringify}},
+	{"read_file", &Builtin{Fn: builtinReadFile}},
+	{"write_file", &Builtin{Fn: builtinWriteFile}},
+	{"append_file", &Builtin{Fn: builtinAppendFile}},
+	{"list_dir", &Builtin{Fn: builtinListDir}},
+	{"exists", &Builtin{Fn: builtinExists}},
+	{"remove", &Builtin{Fn: builtinRemove}},
+	{"print", &Builtin{Fn: builtinPrint}},
+	{"printf", &Builtin{Fn: builtinPrintf}},
+	{"format", &Builtin{Fn: builtinFormat}},
+	{"input", &Builtin{Fn: builtinInput}},
+	{"read_line", &Builtin{Fn: builtinReadLine}},
+	{"abs", &Builtin{Fn: builtinAbs}},
+	{"min", &Builtin{Fn: builtinMin}},
+	{"max", &Builtin{Fn: builtinMax}},
+	{"pow", &Builtin{Fn: builtinPow}},
+	{"sqrt", &Builtin{Fn: builtinSqrt}},
+	{"floor", &Builtin{Fn: roundingFunction("floor", math.Floor)}},
+	{"ceil", &Builtin{Fn: roundingFunction("ceil", math.Ceil)}},
+	{"round", &Builtin{Fn: roundingFunction("round", math.Round)}},
+	{"clamp", &Builtin{Fn: builtinClamp}},
+	{"sin", &Builtin{Fn: floatFunction("sin", math.Sin)}},
+	{"cos", &Builtin{Fn: floatFunction("cos", math.Cos)}},
+	{"tan", &Builtin{Fn: floatFunction("tan", math.Tan)}},
+	{"asin", &Builtin{Fn: floatFunction("asin", math.Asin)}},
+	{"acos", &Builtin{Fn: floatFunction("acos", math.Acos)}},
+	{"atan", &Builtin{Fn: builtinAtan}},
+	{"exp", &Builtin{Fn: floatFunction("exp", math.Exp)}},
+	{"log", &Builtin{Fn: builtinLog}},
+	{"PI", &Builtin{Value: &Float{Value: math.Pi}}},
+	{"E", &Builtin{Value: &Float{Value: math.E}}},
+	{"MAX_INT", &Builtin{Value: &Integer{Value: math.MaxInt64}}},
+	{"MIN_INT", &Builtin{Value: &Integer{Value: math.MinInt64}}},
+	{"random", &Builtin{Fn: builtinRandom}},
+	{"random_int", &Builtin{Fn: builtinRandomInt}},
+	{"shuffle", &Builtin{Fn: builtinShuffle}},
+	{"choice", &Builtin{Fn: builtinChoice}},
+	{"now", &Builtin{Fn: builtinNow}},
+	{"unix", &Builtin{Fn: builtinUnix}},
+	{"format_time", &Builtin{Fn: builtinFormatTime}},
+	{"parse_time", &Builtin{Fn: builtinParseTime}},
+	{"duration", &Builtin{Fn: builtinDuration}},
+	{"format_duration", &Builtin{Fn: builtinFormatDuration}},
+	{"sleep", &Builtin{Fn: builtinSleep}},
+	{"MILLISECOND", &Builtin{Value: &Integer{Value: 1}}},
+	{"SECOND", &Builtin{Value: &Integer{Value: 1000}}},
+	{"MINUTE", &Builtin{Value: &Integer{Value: 60 * 1000}}},
+	{"HOUR", &Builtin{Value: &Integer{Value: 60 * 60 * 1000}}},
+	{"DAY", &Builtin{Value: &Integer{Value: 24 * 60 * 60 * 1000}}},
+	{"match", &Builtin{Fn: builtinMatch}},
+	{"captures", &Builtin{Fn: builtinCaptures}},
+	{"find_all", &Builtin{Fn: builtinFindAll}},
+	{"replace_regex", &Builtin{Fn: builtinReplaceRegex}},
+	{"split_regex", &Builtin{Fn: builtinSplitRegex}},
+	{"type", &Builtin{Fn: builtinType}},
+	{"int", &Builtin{Fn: builtinInt}},
+	{"float", &Builtin{Fn: builtinFloat}},
+	{"str", &Builtin{Fn: builtinStr}},
+	{"bool", &Builtin{Fn: builtinBool}},
+	{"is_int", &Builtin{Fn: typePredicate("integer")}},
+	{"is_float", &Builtin{Fn: typePredicate("float")}},
+	{"is_number", &Builtin{Fn: typePredicate("integer", "float")}},
+	{"is_string", &Builtin{Fn: typePredicate("string")}},
+	{"is_bool", &Builtin{Fn: typePredicate("boolean")}},
+	{"is_array", &Builtin{Fn: typePredicate("array")}},
+	{"is_hash", &Builtin{Fn: typePredicate("hash")}},
+	{"is_null", &Builtin{Fn: typePredicate("null")}},
+	{"is_function", &Builtin{Fn: typePredicate("function")}},
 }
 
 func GetBuiltinByName(name string) *Builtin {
@@ -140,3 +300,57 @@ func GetBuiltinByName(name string) *Builtin {
 func newError(format string, a ...interface{}) *Error {
 	return &Error{Message: fmt.Sprintf(format, a...)}
 }
+
+// checkArity reports an error unless a builtin got between min and max
+// arguments.
+func checkArity(args []Representation, min int, max int) *Error {
+	if len(args) >= min && len(args) <= max {
+		return nil
+	}
+	if min == max {
+		return newError("wrong number of arguments, got=%d, want=%d", len(args), min)
+	}
+	if max == min+1 {
+		return newError("wrong number of arguments, got=%d, want=%d or %d", len(args), min, max)
+	}
+	return newError("wrong number of arguments, got=%d, want=%d to %d", len(args), min, max)
+}
+
+// stringArgument returns the argument of a builtin at index, which must be a
+// string.
+func stringArgument(name string, args []Representation, index int) (string, *Error) {
+	s, ok := args[index].(*String)
+	if !ok {
+		if len(args) == 1 {
+			return "", newError("argument to `%s` must be a string, got %s", name, args[index].Type())
+		}
+		return "", newError("argument %d to `%s` must be a string, got %s", index+1, name, args[index].Type())
+	}
+	return s.Value, nil
+}
+
+// integerArgument returns the argument of a builtin at index, which must be
+// an integer.
+func integerArgument(name string, args []Representation, index int) (int64, *Error) {
+	n, ok := args[index].(*Integer)
+	if !ok {
+		if len(args) == 1 {
+			return 0, newError("argument to `%s` must be an integer, got %s", name, args[index].Type())
+		}
+		return 0, newError("argument %d to `%s` must be an integer, got %s", index+1, name, args[index].Type())
+	}
+	return n.Value, nil
+}
+
+// stringArguments returns the arguments of a builtin that takes only strings.
+func stringArguments(name string, args []Representation) ([]string, *Error) {
+	strs := make([]string, len(args))
+	for i := range args {
+		s, err := stringArgument(name, args, i)
+		if err != nil {
+			return nil, err
+		}
+		strs[i] = s
+	}
+	return strs, nil
+}
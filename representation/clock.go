@@ -0,0 +1,153 @@
+package representation
+
+import (
+	"math"
+	"time"
+)
+
+// Times are integers counting the milliseconds since the Unix epoch, and
+// durations integers counting milliseconds, so that they are compared and
+// added like any other integers: now() + 2 * HOUR.
+
+// now returns the current time of the host's clock.
+func (h *Host) now() time.Time {
+	if h.Clock == nil {
+		return time.Now()
+	}
+	return h.Clock()
+}
+
+// location returns the time zone named by the optional argument of a builtin
+// at index, UTC when there is none.
+func location(name string, args []Representation, index int) (*time.Location, *Error) {
+	if len(args) <= index {
+		return time.UTC, nil
+	}
+	zone, err := stringArgument(name, args, index)
+	if err != nil {
+		return nil, err
+	}
+	loc, loadErr := time.LoadLocation(zone)
+	if loadErr != nil {
+		return nil, newError("%s: unknown time zone %q", name, zone)
+	}
+	return loc, nil
+}
+
+func builtinNow(host *Host, args ...Representation) Representation {
+	if err := checkArity(args, 0, 0); err != nil {
+		return err
+	}
+	return &Integer{Value: host.now().UnixMilli()}
+}
+
+func builtinUnix(host *Host, args ...Representation) Representation {
+	if err := checkArity(args, 0, 0); err != nil {
+		return err
+	}
+	return &Integer{Value: host.now().Unix()}
+}
+
+func builtinFormatTime(host *Host, args ...Representation) Representation {
+	if err := checkArity(args, 2, 3); err != nil {
+		return err
+	}
+	t, err := integerArgument("format_time", args, 0)
+	if err != nil {
+		return err
+	}
+	layout, err := stringArgument("format_time", args, 1)
+	if err != nil {
+		return err
+	}
+	loc, err := location("format_time", args, 2)
+	if err != nil {
+		return err
+	}
+	return &String{Value: time.UnixMilli(t).In(loc).Format(layout)}
+}
+
+func builtinParseTime(host *Host, args ...Representation) Representation {
+	if err := checkArity(args, 2, 3); err != nil {
+		return err
+	}
+	value, err := stringArgument("parse_time", args, 0)
+	if err != nil {
+		return err
+	}
+	layout, err := stringArgument("parse_time", args, 1)
+	if err != nil {
+		return err
+	}
+	loc, err := location("parse_time", args, 2)
+	if err != nil {
+		return err
+	}
+
+	t, parseErr := time.ParseInLocation(layout, value, loc)
+	if parseErr != nil {
+		return newError("parse_time: %s", parseErr)
+	}
+	return &Integer{Value: t.UnixMilli()}
+}
+
+func builtinDuration(host *Host, args ...Representation) Representation {
+	if err := checkArity(args, 1, 1); err != nil {
+		return err
+	}
+	s, err := stringArgument("duration", args, 0)
+	if err != nil {
+		return err
+	}
+	d, parseErr := time.ParseDuration(s)
+	if parseErr != nil {
+		return newError("duration: %s", parseErr)
+	}
+	return &Integer{Value: d.Milliseconds()}
+}
+
+func builtinFormatDuration(host *Host, args ...Representation) Representation {
+	if err := checkArity(args, 1, 1); err != nil {
+		return err
+	}
+	ms, err := integerArgument("format_duration", args, 0)
+	if err != nil {
+		return err
+	}
+	if ms > math.MaxInt64/int64(time.Millisecond) || ms < math.MinInt64/int64(time.Millisecond) {
+		return newError("format_duration: %d milliseconds is out of range", ms)
+	}
+	return &String{Value: (time.Duration(ms) * time.Millisecond).String()}
+}
+
+// builtinSleep pauses for a number of milliseconds, stopping the program if
+// the host's context is cancelled in the meantime.
+func builtinSleep(host *Host, args ...Representation) Representation {
+	if err := checkArity(args, 1, 1); err != nil {
+		return err
+	}
+	ms, err := integerArgument("sleep", args, 0)
+	if err != nil {
+		return err
+	}
+	if ms < 0 {
+		return newError("argument to `sleep` must not be negative, got %d", ms)
+	}
+	if ms > math.MaxInt64/int64(time.Millisecond) {
+		ms = math.MaxInt64 / int64(time.Millisecond)
+	}
+
+	timer := time.NewTimer(time.Duration(ms) * time.Millisecond)
+	defer timer.Stop()
+
+	var done <-chan struct{}
+	if host.Context != nil {
+		done = host.Context.Done()
+	}
+	select {
+	case <-timer.C:
+		return NULL
+	case <-done:
+		return &Halt{Err: host.Context.Err()}
+	}
+}
@@ -0,0 +1,151 @@
+package representation
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// TypeName is the name `type` gives a value. Functions are "function" on
+// every engine, whether they are closures, evaluated functions or builtins.
+func TypeName(value Representation) string {
+	switch value.(type) {
+	case *Closure, *Function, *Builtin, *CompiledFunction:
+		return "function"
+	default:
+		return strings.ToLower(string(value.Type()))
+	}
+}
+
+func builtinType(host *Host, args ...Representation) Representation {
+	if err := checkArity(args, 1, 1); err != nil {
+		return err
+	}
+	return &String{Value: TypeName(args[0])}
+}
+
+// builtinInt converts to an integer: floats are truncated, booleans become 1
+// or 0 and strings are parsed in base 10 or the given base.
+func builtinInt(host *Host, args ...Representation) Representation {
+	if err := checkArity(args, 1, 2); err != nil {
+		return err
+	}
+	if len(args) == 2 {
+		if _, ok := args[0].(*String); !ok {
+			return newError("`int`: a base needs a string to parse, got %s", args[0].Type())
+		}
+	}
+
+	switch arg := args[0].(type) {
+	case *Integer:
+		return arg
+	case *Float:
+		return toInteger("int", math.Trunc(arg.Value))
+	case *Boolean:
+		if arg.Value {
+			return &Integer{Value: 1}
+		}
+		return &Integer{Value: 0}
+	case *String:
+		base := int64(10)
+		if len(args) == 2 {
+			var err *Error
+			if base, err = integerArgument("int", args, 1); err != nil {
+				return err
+			}
+			if base < 2 || base > 36 {
+				return newError("`int`: base %d is not between 2 and 36", base)
+			}
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(arg.Value), int(base), 64)
+		if errors.Is(err, strconv.ErrRange) {
+			return newError("`int`: %q does not fit in an integer", arg.Value)
+		}
+		if err != nil {
+			return newError("`int`: cannot parse %q as an integer", arg.Value)
+		}
+		return &Integer{Value: n}
+	default:
+		return newError("`int`: cannot convert %s to an integer", args[0].Type())
+	}
+}
+
+func builtinFloat(host *Host, args ...Representation) Representation {
+	if err := checkArity(args, 1, 1); err != nil {
+		return err
+	}
+
+	switch arg := args[0].(type) {
+	case *Integer:
+		return &Float{Value: float64(arg.Value)}
+	case *Float:
+		return arg
+	case *Boolean:
+		if arg.Value {
+			return &Float{Value: 1}
+		}
+		return &Float{Value: 0}
+	case *String:
+		f, err := strconv.ParseFloat(strings.TrimSpace(arg.Value), 64)
+		if errors.Is(err, strconv.ErrRange) {
+			return newError("`float`: %q is out of range", arg.Value)
+		}
+		if err != nil {
+			return newError("`float`: cannot parse %q as a float", arg.Value)
+		}
+		return &Float{Value: f}
+	default:
+		return newError("`float`: cannot convert %s to a float", args[0].Type())
+	}
+}
+
+// builtinStr returns strings as they are and the printed form of anything
+// else.
+func builtinStr(host *Host, args ...Representation) Representation {
+	if err := checkArity(args, 1, 1); err != nil {
+		return err
+	}
+	if s, ok := args[0].(*String); ok {
+		return s
+	}
+	return &String{Value: args[0].Inspect()}
+}
+
+// builtinBool parses "true" and "false" from strings, and tells of any other
+// value whether it counts as true in a condition, which everything but false
+// and null does.
+func builtinBool(host *Host, args ...Representation) Representation {
+	if err := checkArity(args, 1, 1); err != nil {
+		return err
+	}
+
+	s, ok := args[0].(*String)
+	if !ok {
+		return NativeBool(truthy(args[0]))
+	}
+	switch strings.TrimSpace(s.Value) {
+	case "true":
+		return TRUE
+	case "false":
+		return FALSE
+	default:
+		return newError("`bool`: cannot parse %q as a boolean", s.Value)
+	}
+}
+
+// typePredicate makes an is_* builtin that checks the type of its argument.
+func typePredicate(names ...string) BuiltinFunction {
+	return func(host *Host, args ...Representation) Representation {
+		if err := checkArity(args, 1, 1); err != nil {
+			return err
+		}
+		name := TypeName(args[0])
+		for _, want := range names {
+			if name == want {
+				return TRUE
+			}
+		}
+		return FALSE
+	}
+}
@@ -3,19 +3,29 @@ package representation
 type Environment struct {
 	store map[string]Representation
 	outer *Environment
+	host  *Host
 }
 
 func NewEnvironment() *Environment {
+	return NewEnvironmentWithHost(NewHost())
+}
+
+func NewEnvironmentWithHost(host *Host) *Environment {
 	s := make(map[string]Representation)
-	return &Environment{store: s, outer: nil}
+	return &Environment{store: s, outer: nil, host: host}
 }
 
 func NewEnclosedEnvironment(outer *Environment) *Environment {
-	env := NewEnvironment()
+	env := NewEnvironmentWithHost(outer.host)
 	env.outer = outer
 	return env
 }
 
+// Host returns the host builtins called from this environment run against.
+func (e *Environment) Host() *Host {
+	return e.host
+}
+
 func (e *Environment) Get(name string) (Representation, bool) {
 	obj, ok := e.store[name]
 	if !ok && e.outer != nil {
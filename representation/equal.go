@@ -0,0 +1,101 @@
+package representation
+
+import (
+	"math"
+	"strings"
+)
+
+// Equal reports whether two values are structurally equal. Numbers are equal
+// when their values are, whether integers or floats. Arrays and hashes are
+// equal when their elements are, other values such as functions only when
+// they are the same value.
+func Equal(a Representation, b Representation) bool {
+	if IsNumber(a) && IsNumber(b) {
+		return compareNumbers(a, b) == 0 && !isNaN(a) && !isNaN(b)
+	}
+
+	switch a := a.(type) {
+	case *String:
+		other, ok := b.(*String)
+		return ok && a.Value == other.Value
+	case *Boolean:
+		other, ok := b.(*Boolean)
+		return ok && a.Value == other.Value
+	case *Null:
+		_, ok := b.(*Null)
+		return ok
+	case *Array:
+		other, ok := b.(*Array)
+		if !ok || len(a.Elements) != len(other.Elements) {
+			return false
+		}
+		for i := range a.Elements {
+			if !Equal(a.Elements[i], other.Elements[i]) {
+				return false
+			}
+		}
+		return true
+	case *Hash:
+		other, ok := b.(*Hash)
+		if !ok || len(a.Pairs) != len(other.Pairs) {
+			return false
+		}
+		for key, pair := range a.Pairs {
+			otherPair, ok := other.Pairs[key]
+			if !ok || !Equal(pair.Value, otherPair.Value) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
+
+func isNaN(value Representation) bool {
+	f, ok := value.(*Float)
+	return ok && math.IsNaN(f.Value)
+}
+
+// Compare orders two numbers, two strings or two arrays, returning a
+// negative number, zero or a positive number as a sorts before, with or after
+// b. Strings are ordered by their bytes, which for UTF-8 is the order of
+// their code points, and arrays by their first differing element, a prefix
+// going first.
+func Compare(a Representation, b Representation) (int, *Error) {
+	if IsNumber(a) && IsNumber(b) {
+		return compareNumbers(a, b), nil
+	}
+
+	switch a := a.(type) {
+	case *String:
+		if other, ok := b.(*String); ok {
+			return strings.Compare(a.Value, other.Value), nil
+		}
+	case *Array:
+		if other, ok := b.(*Array); ok {
+			return compareArrays(a, other)
+		}
+	}
+	return 0, newError("cannot compare %s with %s", a.Type(), b.Type())
+}
+
+// Ordered reports whether values of a type can be ordered by Compare.
+func Ordered(value Representation) bool {
+	switch value.(type) {
+	case *Integer, *Float, *String, *Array:
+		return true
+	default:
+		return false
+	}
+}
+
+func compareArrays(a *Array, b *Array) (int, *Error) {
+	for i := 0; i < len(a.Elements) && i < len(b.Elements); i++ {
+		order, err := Compare(a.Elements[i], b.Elements[i])
+		if err != nil || order != 0 {
+			return order, err
+		}
+	}
+	return len(a.Elements) - len(b.Elements), nil
+}
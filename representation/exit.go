@@ -0,0 +1,13 @@
+package representation
+
+import "fmt"
+
+// Exit is returned by the `exit` builtin. It also implements error so that an
+// engine can unwind with it and the embedder can read the requested status.
+type Exit struct {
+	Code int
+}
+
+func (e *Exit) Type() RepresentationType { return EXIT_REPR }
+func (e *Exit) Inspect() string          { return fmt.Sprintf("exit(%d)", e.Code) }
+func (e *Exit) Error() string            { return fmt.Sprintf("exit status %d", e.Code) }
@@ -0,0 +1,33 @@
+package representation
+
+// Failure is returned by `assert` and `assert_eq` when a check does not hold.
+// Like Exit, it implements error so that the engine stops with it.
+type Failure struct {
+	Message string
+}
+
+func (f *Failure) Type() RepresentationType { return FAILURE_REPR }
+func (f *Failure) Inspect() string          { return "FAILURE: " + f.Message }
+func (f *Failure) Error() string            { return f.Message }
+
+// Halt carries an error that stopped a function called from a builtin, or
+// the cancellation of the host's context, so that the engine which called the
+// builtin stops with it too.
+type Halt struct {
+	Err error
+}
+
+func (h *Halt) Type() RepresentationType { return HALT_REPR }
+func (h *Halt) Inspect() string          { return "HALT: " + h.Err.Error() }
+func (h *Halt) Error() string            { return h.Err.Error() }
+func (h *Halt) Unwrap() error            { return h.Err }
+
+// Stops reports whether a value returned by Host.Call ends the program
+// rather than being a result to use.
+func Stops(result Representation) bool {
+	if _, ok := result.(error); ok {
+		return true
+	}
+	_, ok := result.(*Error)
+	return ok
+}
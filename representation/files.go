@@ -0,0 +1,191 @@
+package representation
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FilePolicy decides which paths the file builtins may touch. Paths are
+// resolved against the working directory, following symbolic links, before
+// they are checked against Roots.
+type FilePolicy struct {
+	// Roots are the directories scripts may access, including everything
+	// below them. An empty list allows every path.
+	Roots []string
+
+	// ReadOnly rejects writing and removing files.
+	ReadOnly bool
+}
+
+// resolve returns the absolute form of path if the policy allows the access.
+func (p *FilePolicy) resolve(path string, write bool) (string, error) {
+	if p == nil {
+		return "", errors.New("file access is disabled")
+	}
+	if write && p.ReadOnly {
+		return "", errors.New("file system is read-only")
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if len(p.Roots) == 0 {
+		return abs, nil
+	}
+
+	real := realPath(abs)
+	for _, root := range p.Roots {
+		rootAbs, err := filepath.Abs(root)
+		if err != nil {
+			continue
+		}
+		if within(realPath(rootAbs), real) {
+			return abs, nil
+		}
+	}
+	return "", fmt.Errorf("access to %s is outside the allowed directories", path)
+}
+
+// realPath resolves symbolic links in the longest prefix of path that exists.
+func realPath(path string) string {
+	existing, rest := path, ""
+	for {
+		if resolved, err := filepath.EvalSymlinks(existing); err == nil {
+			return filepath.Join(resolved, rest)
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return path
+		}
+		rest = filepath.Join(filepath.Base(existing), rest)
+		existing = parent
+	}
+}
+
+func within(root string, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func builtinReadFile(host *Host, args ...Representation) Representation {
+	path, err := filePath("read_file", host, args, 1, false)
+	if err != nil {
+		return err
+	}
+
+	content, readErr := os.ReadFile(path)
+	if readErr != nil {
+		return fileError(readErr, args[0])
+	}
+	return &String{Value: string(content)}
+}
+
+func builtinWriteFile(host *Host, args ...Representation) Representation {
+	return writeFile("write_file", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, host, args)
+}
+
+func builtinAppendFile(host *Host, args ...Representation) Representation {
+	return writeFile("append_file", os.O_WRONLY|os.O_CREATE|os.O_APPEND, host, args)
+}
+
+func writeFile(name string, flag int, host *Host, args []Representation) Representation {
+	path, err := filePath(name, host, args, 2, true)
+	if err != nil {
+		return err
+	}
+	content, err := stringArgument(name, args, 1)
+	if err != nil {
+		return err
+	}
+
+	file, openErr := os.OpenFile(path, flag, 0o644)
+	if openErr != nil {
+		return fileError(openErr, args[0])
+	}
+	_, writeErr := file.WriteString(content)
+	if closeErr := file.Close(); writeErr == nil {
+		writeErr = closeErr
+	}
+	if writeErr != nil {
+		return fileError(writeErr, args[0])
+	}
+	return nil
+}
+
+func builtinListDir(host *Host, args ...Representation) Representation {
+	path, err := filePath("list_dir", host, args, 1, false)
+	if err != nil {
+		return err
+	}
+
+	entries, readErr := os.ReadDir(path)
+	if readErr != nil {
+		return fileError(readErr, args[0])
+	}
+
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name()
+	}
+	sort.Strings(names)
+	return stringArray(names)
+}
+
+func builtinExists(host *Host, args ...Representation) Representation {
+	path, err := filePath("exists", host, args, 1, false)
+	if err != nil {
+		return err
+	}
+
+	_, statErr := os.Stat(path)
+	if statErr != nil && !errors.Is(statErr, fs.ErrNotExist) {
+		return fileError(statErr, args[0])
+	}
+	return NativeBool(statErr == nil)
+}
+
+func builtinRemove(host *Host, args ...Representation) Representation {
+	path, err := filePath("remove", host, args, 1, true)
+	if err != nil {
+		return err
+	}
+
+	if removeErr := os.Remove(path); removeErr != nil {
+		return fileError(removeErr, args[0])
+	}
+	return nil
+}
+
+// filePath checks the arguments of a file builtin, whose first argument is a
+// path, and resolves the path against the host's policy.
+func filePath(name string, host *Host, args []Representation, arity int, write bool) (string, *Error) {
+	if err := checkArity(args, arity, arity); err != nil {
+		return "", err
+	}
+	path, err := stringArgument(name, args, 0)
+	if err != nil {
+		return "", err
+	}
+
+	resolved, policyErr := host.FS.resolve(path, write)
+	if policyErr != nil {
+		return "", newError("%s: %s", name, policyErr)
+	}
+	return resolved, nil
+}
+
+// fileError reports an operating system error under the path the script
+// used rather than the resolved one.
+func fileError(err error, path Representation) *Error {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return newError("%s %s: %s", pathErr.Op, path.(*String).Value, pathErr.Err)
+	}
+	return newError("%s", err)
+}
@@ -0,0 +1,22 @@
+package representation
+
+import (
+	"strconv"
+	"strings"
+)
+
+type Float struct {
+	Value float64
+}
+
+func (f *Float) Type() RepresentationType { return FLOAT_REPR }
+
+// Inspect prints the shortest text that reads back as the same float, always
+// with a fraction or an exponent so that it cannot be taken for an integer.
+func (f *Float) Inspect() string {
+	s := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if strings.ContainsAny(s, ".eIN") {
+		return s
+	}
+	return s + ".0"
+}
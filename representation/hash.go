@@ -3,6 +3,7 @@ package representation
 import (
 	"bytes"
 	"fmt"
+	"sort"
 	"strings"
 )
 
@@ -13,13 +14,75 @@ type HashPair struct {
 
 type Hash struct {
 	Pairs map[HashKey]HashPair
+
+	// Keys lists the keys of Pairs in insertion order. Set and Delete keep
+	// it up to date.
+	Keys []HashKey
+}
+
+// NewHash returns an empty hash.
+func NewHash() *Hash {
+	return &Hash{Pairs: map[HashKey]HashPair{}}
+}
+
+// Set adds a pair after the existing ones, or replaces the value of a key
+// in place.
+func (h *Hash) Set(key HashKey, pair HashPair) {
+	if _, ok := h.Pairs[key]; !ok {
+		h.Keys = append(h.Keys, key)
+	}
+	h.Pairs[key] = pair
+}
+
+// Delete removes a key, keeping the order of the others.
+func (h *Hash) Delete(key HashKey) {
+	if _, ok := h.Pairs[key]; !ok {
+		return
+	}
+	delete(h.Pairs, key)
+	for i, k := range h.Keys {
+		if k == key {
+			h.Keys = append(h.Keys[:i:i], h.Keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// Ordered returns the pairs in insertion order. Hashes built without Keys
+// have their pairs sorted by their inspected keys instead.
+func (h *Hash) Ordered() []HashPair {
+	pairs := make([]HashPair, 0, len(h.Pairs))
+	if len(h.Keys) == len(h.Pairs) {
+		for _, key := range h.Keys {
+			pairs = append(pairs, h.Pairs[key])
+		}
+		return pairs
+	}
+
+	for _, pair := range h.Pairs {
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(i int, j int) bool {
+		return pairs[i].Key.Inspect() < pairs[j].Key.Inspect()
+	})
+	return pairs
+}
+
+// Copy returns a hash with the same pairs, which can be changed without
+// changing h.
+func (h *Hash) Copy() *Hash {
+	hash := NewHash()
+	for _, pair := range h.Ordered() {
+		hash.Set(pair.Key.(Hashable).HashKey(), pair)
+	}
+	return hash
 }
 
 func (h *Hash) Type() RepresentationType { return HASH_REPR }
 func (h *Hash) Inspect() string {
 	var out bytes.Buffer
 	pairs := []string{}
-	for _, pair := range h.Pairs {
+	for _, pair := range h.Ordered() {
 		pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), pair.Value.Inspect()))
 	}
 	out.WriteString("{")
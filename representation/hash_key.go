@@ -1,5 +1,10 @@
 package representation
 
+import (
+	"encoding/binary"
+	"hash/fnv"
+)
+
 type HashKey struct {
 	Type  RepresentationType
 	Value uint64
@@ -8,3 +13,38 @@ type HashKey struct {
 type Hashable interface {
 	HashKey() HashKey
 }
+
+// HashKeyOf returns the key a value is stored under in a hash, or false when
+// it cannot be a hash key. Arrays can be keys when all their elements can.
+func HashKeyOf(value Representation) (HashKey, bool) {
+	if arr, ok := value.(*Array); ok {
+		return arrayHashKey(arr)
+	}
+	hashable, ok := value.(Hashable)
+	if !ok {
+		return HashKey{}, false
+	}
+	return hashable.HashKey(), true
+}
+
+// HashKey combines the keys of the elements, so that equal arrays get equal
+// keys. It is only meaningful when HashKeyOf accepts the array.
+func (ao *Array) HashKey() HashKey {
+	key, _ := arrayHashKey(ao)
+	return key
+}
+
+func arrayHashKey(arr *Array) (HashKey, bool) {
+	h := fnv.New64a()
+	buf := make([]byte, 8)
+	for _, element := range arr.Elements {
+		key, ok := HashKeyOf(element)
+		if !ok {
+			return HashKey{}, false
+		}
+		h.Write([]byte(key.Type))
+		binary.LittleEndian.PutUint64(buf, key.Value)
+		h.Write(buf)
+	}
+	return HashKey{Type: ARRAY_REPR, Value: h.Sum64()}, true
+}
@@ -0,0 +1,107 @@
+package representation
+
+func builtinKeys(host *Host, args ...Representation) Representation {
+	hash, err := singleHash("keys", args)
+	if err != nil {
+		return err
+	}
+
+	keys := []Representation{}
+	for _, pair := range hash.Ordered() {
+		keys = append(keys, pair.Key)
+	}
+	return &Array{Elements: keys}
+}
+
+func builtinValues(host *Host, args ...Representation) Representation {
+	hash, err := singleHash("values", args)
+	if err != nil {
+		return err
+	}
+
+	values := []Representation{}
+	for _, pair := range hash.Ordered() {
+		values = append(values, pair.Value)
+	}
+	return &Array{Elements: values}
+}
+
+func builtinEntries(host *Host, args ...Representation) Representation {
+	hash, err := singleHash("entries", args)
+	if err != nil {
+		return err
+	}
+
+	entries := []Representation{}
+	for _, pair := range hash.Ordered() {
+		entries = append(entries, &Array{Elements: []Representation{pair.Key, pair.Value}})
+	}
+	return &Array{Elements: entries}
+}
+
+func builtinHasKey(host *Host, args ...Representation) Representation {
+	hash, key, err := hashAndKey("has_key", args)
+	if err != nil {
+		return err
+	}
+
+	_, ok := hash.Pairs[key]
+	return NativeBool(ok)
+}
+
+func builtinDelete(host *Host, args ...Representation) Representation {
+	hash, key, err := hashAndKey("delete", args)
+	if err != nil {
+		return err
+	}
+
+	result := hash.Copy()
+	result.Delete(key)
+	return result
+}
+
+func builtinMerge(host *Host, args ...Representation) Representation {
+	if len(args) == 0 {
+		return newError("wrong number of arguments, got=0, want=1 or more")
+	}
+
+	result := NewHash()
+	for i, arg := range args {
+		hash, ok := arg.(*Hash)
+		if !ok {
+			return newError("argument %d to `merge` must be a hash, got %s", i+1, arg.Type())
+		}
+		for _, pair := range hash.Ordered() {
+			result.Set(pair.Key.(Hashable).HashKey(), pair)
+		}
+	}
+	return result
+}
+
+// singleHash checks the arguments of builtins called as f(hash).
+func singleHash(name string, args []Representation) (*Hash, *Error) {
+	if err := checkArity(args, 1, 1); err != nil {
+		return nil, err
+	}
+	hash, ok := args[0].(*Hash)
+	if !ok {
+		return nil, newError("argument to `%s` must be a hash, got %s", name, args[0].Type())
+	}
+	return hash, nil
+}
+
+// hashAndKey checks the arguments of builtins called as f(hash, key).
+func hashAndKey(name string, args []Representation) (*Hash, HashKey, *Error) {
+	if err := checkArity(args, 2, 2); err != nil {
+		return nil, HashKey{}, err
+	}
+	hash, ok := args[0].(*Hash)
+	if !ok {
+		return nil, HashKey{}, newError("argument 1 to `%s` must be a hash, got %s", name, args[0].Type())
+	}
+	key, ok := HashKeyOf(args[1])
+	if !ok {
+		return nil, HashKey{}, newError("unusable as hash key: %s", args[1].Type())
+	}
+	return hash, key, nil
+}
@@ -0,0 +1,114 @@
+package representation
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"math/rand"
+	"regexp"
+	"time"
+)
+
+// Host is the state an engine makes available to the builtins it calls. It is
+// provided by the embedder, so the same builtins can run as a command-line
+// tool or inside a service with a restricted view of the outside world.
+type Host struct {
+	// Args are the arguments passed to the script, excluding its name.
+	Args []string
+
+	// LookupEnv reads an environment variable. A nil LookupEnv hides the
+	// environment from scripts.
+	LookupEnv func(name string) (string, bool)
+
+	// Stdout receives what scripts print. A nil Stdout writes to the
+	// standard output of the process.
+	Stdout io.Writer
+
+	// Stdin is read by `input` and `read_line`. A nil Stdin reads as empty.
+	Stdin io.Reader
+
+	// FS is the policy of the file builtins. A nil FS denies all file access.
+	FS *FilePolicy
+
+	// Rand generates the numbers of the random builtins. Give it a fixed
+	// seed, as in rand.New(rand.NewSource(seed)), for runs that repeat the
+	// same sequence. A nil Rand is seeded from the clock.
+	Rand *rand.Rand
+
+	// Clock tells the time builtins the current time. Set it to a fixed
+	// time to freeze the clock. A nil Clock reads the system clock.
+	Clock func() time.Time
+
+	// Context cancels the program: once it is done the engines stop before
+	// their next function call, and `sleep` returns early. A nil Context is
+	// never cancelled.
+	Context context.Context
+
+	// Strict makes indexing an array or slicing out of range an error, where
+	// it otherwise yields null or is cut to the bounds of the value.
+	Strict bool
+
+	// Call applies a function value to arguments. The engine running the
+	// program sets it, so that builtins can call back into the program. A
+	// result that implements error, or is an *Error, must be returned by the
+	// builtin as it is.
+	Call func(fn Representation, args ...Representation) Representation
+
+	// Test is called by the `test` builtin with the name and body of each
+	// test. A nil Test runs the body straight away.
+	Test func(name string, fn Representation) Representation
+
+	// input buffers Stdin between reads, and inputSource is the reader it
+	// was made for.
+	input       *bufio.Reader
+	inputSource io.Reader
+
+	// patterns caches the regular expressions the program has compiled.
+	patterns map[string]*regexp.Regexp
+
+	// depth counts the function calls the evaluator is inside.
+	depth int
+}
+
+// MaxCallDepth is how deeply the evaluator nests function calls before it
+// stops with a stack overflow, as the VM does once it runs out of frames.
+const MaxCallDepth = 1024
+
+// NewHost returns a host with no arguments and no access to the environment
+// or the file system.
+func NewHost() *Host {
+	return &Host{Args: []string{}}
+}
+
+// Err returns the error of Context once it is cancelled, which the engines
+// stop with.
+func (h *Host) Err() error {
+	if h.Context == nil {
+		return nil
+	}
+	return h.Context.Err()
+}
+
+// EnterCall records that the evaluator calls a function, failing once calls
+// are nested more than MaxCallDepth deep. A call that entered is left with
+// LeaveCall.
+func (h *Host) EnterCall() *Error {
+	if h.depth >= MaxCallDepth {
+		return newError("stack overflow")
+	}
+	h.depth++
+	return nil
+}
+
+// LeaveCall records that a call recorded by EnterCall returned.
+func (h *Host) LeaveCall() {
+	h.depth--
+}
+
+// call applies fn to args through Call, for builtins that take functions.
+func (h *Host) call(fn Representation, args ...Representation) Representation {
+	if h.Call == nil {
+		return newError("builtins cannot call functions on this engine")
+	}
+	return h.Call(fn, args...)
+}
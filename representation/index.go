@@ -0,0 +1,78 @@
+package representation
+
+// Index returns the element of an array at index, counting from the end when
+// index is negative, so that -1 is the last element. An index out of range
+// yields null, or an error on a strict host.
+func (h *Host) Index(arr *Array, index int64) (Representation, *Error) {
+	length := int64(len(arr.Elements))
+	i := index
+	if i < 0 {
+		i += length
+	}
+	if i < 0 || i >= length {
+		if h.Strict {
+			return nil, newError("index out of range: %d with length %d", index, length)
+		}
+		return NULL, nil
+	}
+	return arr.Elements[i], nil
+}
+
+// Slice returns the part of an array or a string from start up to end, which
+// are integers or null for the start and the end of the value. Strings are
+// sliced by characters. Negative bounds count from the end, and bounds out of
+// range are moved to the nearest end, or are an error on a strict host.
+func (h *Host) Slice(value Representation, start Representation, end Representation) (Representation, *Error) {
+	var length int64
+	switch value := value.(type) {
+	case *Array:
+		length = int64(len(value.Elements))
+	case *String:
+		length = int64(len([]rune(value.Value)))
+	default:
+		return nil, newError("slice operator not supported: %s", value.Type())
+	}
+
+	low, err := sliceBound(start, 0)
+	if err != nil {
+		return nil, err
+	}
+	high, err := sliceBound(end, length)
+	if err != nil {
+		return nil, err
+	}
+
+	from, to := low, high
+	if from < 0 {
+		from += length
+	}
+	if to < 0 {
+		to += length
+	}
+	if from < 0 || to > length || from > to {
+		if h.Strict {
+			return nil, newError("slice bounds out of range: [%d:%d] with length %d", low, high, length)
+		}
+		from, to = min(max(from, 0), length), min(max(to, 0), length)
+		to = max(from, to)
+	}
+
+	if arr, ok := value.(*Array); ok {
+		elements := make([]Representation, to-from)
+		copy(elements, arr.Elements[from:to])
+		return &Array{Elements: elements}, nil
+	}
+	return &String{Value: string([]rune(value.(*String).Value)[from:to])}, nil
+}
+
+// sliceBound returns a bound of a slice, or fallback when it was left out.
+func sliceBound(bound Representation, fallback int64) (int64, *Error) {
+	switch bound := bound.(type) {
+	case *Null:
+		return fallback, nil
+	case *Integer:
+		return bound.Value, nil
+	default:
+		return 0, newError("slice index must be an integer, got %s", bound.Type())
+	}
+}
@@ -0,0 +1,244 @@
+package representation
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+func builtinJSONParse(host *Host, args ...Representation) Representation {
+	if err := checkArity(args, 1, 1); err != nil {
+		return err
+	}
+	s, err := stringArgument("json_parse", args, 0)
+	if err != nil {
+		return err
+	}
+
+	d := &jsonDecoder{input: s, dec: json.NewDecoder(strings.NewReader(s))}
+	d.dec.UseNumber()
+
+	value, decodeErr := d.value()
+	if decodeErr == nil {
+		offset := d.offset()
+		if _, tokenErr := d.dec.Token(); tokenErr != io.EOF {
+			decodeErr = &jsonError{offset: offset, message: "unexpected data after the value"}
+		}
+	}
+	if decodeErr != nil {
+		return newError("invalid JSON at offset %d: %s", decodeErr.offset, decodeErr.message)
+	}
+	return value
+}
+
+type jsonDecoder struct {
+	input string
+	dec   *json.Decoder
+}
+
+type jsonError struct {
+	offset  int64
+	message string
+}
+
+// offset returns the position of the next token in the input.
+func (d *jsonDecoder) offset() int64 {
+	offset := d.dec.InputOffset()
+	rest := d.input[offset:]
+	return offset + int64(len(rest)-len(strings.TrimLeft(rest, " \t\r\n")))
+}
+
+func (d *jsonDecoder) token() (json.Token, *jsonError) {
+	offset := d.offset()
+	tok, err := d.dec.Token()
+	if err == nil {
+		return tok, nil
+	}
+
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		(errors.As(err, &syntaxErr) && syntaxErr.Offset >= int64(len(d.input))):
+		return nil, &jsonError{offset: int64(len(d.input)), message: "unexpected end of input"}
+	case syntaxErr != nil && strings.HasPrefix(syntaxErr.Error(), "invalid character"):
+		// The offset counts the invalid character as read.
+		return nil, &jsonError{offset: syntaxErr.Offset - 1, message: err.Error()}
+	case syntaxErr != nil:
+		return nil, &jsonError{offset: syntaxErr.Offset, message: err.Error()}
+	default:
+		return nil, &jsonError{offset: offset, message: err.Error()}
+	}
+}
+
+func (d *jsonDecoder) value() (Representation, *jsonError) {
+	offset := d.offset()
+	tok, err := d.token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok := tok.(type) {
+	case json.Delim:
+		if tok == '[' {
+			elements := []Representation{}
+			for d.dec.More() {
+				element, err := d.value()
+				if err != nil {
+					return nil, err
+				}
+				elements = append(elements, element)
+			}
+			if _, err := d.token(); err != nil {
+				return nil, err
+			}
+			return &Array{Elements: elements}, nil
+		}
+
+		hash := NewHash()
+		for d.dec.More() {
+			keyToken, err := d.token()
+			if err != nil {
+				return nil, err
+			}
+			key := &String{Value: keyToken.(string)}
+
+			value, err := d.value()
+			if err != nil {
+				return nil, err
+			}
+			hash.Set(key.HashKey(), HashPair{Key: key, Value: value})
+		}
+		if _, err := d.token(); err != nil {
+			return nil, err
+		}
+		return hash, nil
+	case string:
+		return &String{Value: tok}, nil
+	case json.Number:
+		if n, numberErr := tok.Int64(); numberErr == nil {
+			return &Integer{Value: n}, nil
+		}
+		if !strings.ContainsAny(tok.String(), ".eE") {
+			return nil, &jsonError{offset: offset, message: fmt.Sprintf("number %s does not fit in an integer", tok)}
+		}
+		f, numberErr := tok.Float64()
+		if numberErr != nil {
+			return nil, &jsonError{offset: offset, message: fmt.Sprintf("number %s is out of range", tok)}
+		}
+		return &Float{Value: f}, nil
+	case bool:
+		return NativeBool(tok), nil
+	default:
+		return NULL, nil
+	}
+}
+
+func builtinJSONStringify(host *Host, args ...Representation) Representation {
+	if err := checkArity(args, 1, 2); err != nil {
+		return err
+	}
+
+	indent := ""
+	if len(args) == 2 {
+		switch arg := args[1].(type) {
+		case *Integer:
+			if arg.Value < 0 {
+				return newError("negative indent to `json_stringify`: %d", arg.Value)
+			}
+			indent = strings.Repeat(" ", int(arg.Value))
+		case *String:
+			indent = arg.Value
+		default:
+			return newError("argument 2 to `json_stringify` must be an integer or a string, got %s", arg.Type())
+		}
+	}
+
+	var out bytes.Buffer
+	if err := encodeJSON(&out, args[0], map[Representation]bool{}); err != nil {
+		return err
+	}
+	if indent == "" {
+		return &String{Value: out.String()}
+	}
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, out.Bytes(), "", indent); err != nil {
+		return newError("json_stringify: %s", err)
+	}
+	return &String{Value: indented.String()}
+}
+
+// encodeJSON writes value as compact JSON. Arrays and hashes being written
+// are kept in visiting, so that a structure containing itself is rejected.
+func encodeJSON(out *bytes.Buffer, value Representation, visiting map[Representation]bool) *Error {
+	switch value := value.(type) {
+	case *Null:
+		out.WriteString("null")
+	case *Boolean, *Integer:
+		out.WriteString(value.Inspect())
+	case *Float:
+		if math.IsNaN(value.Value) || math.IsInf(value.Value, 0) {
+			return newError("cannot convert %s to JSON", value.Inspect())
+		}
+		out.WriteString(value.Inspect())
+	case *String:
+		out.WriteString(quoteJSON(value.Value))
+	case *Array:
+		if visiting[value] {
+			return newError("cannot convert a cyclic array to JSON")
+		}
+		visiting[value] = true
+		defer delete(visiting, value)
+
+		out.WriteByte('[')
+		for i, element := range value.Elements {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			if err := encodeJSON(out, element, visiting); err != nil {
+				return err
+			}
+		}
+		out.WriteByte(']')
+	case *Hash:
+		if visiting[value] {
+			return newError("cannot convert a cyclic hash to JSON")
+		}
+		visiting[value] = true
+		defer delete(visiting, value)
+
+		out.WriteByte('{')
+		for i, pair := range value.Ordered() {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			switch key := pair.Key.(type) {
+			case *String:
+				out.WriteString(quoteJSON(key.Value))
+			default:
+				// JSON keys are strings, so other keys are written as text.
+				out.WriteString(quoteJSON(key.Inspect()))
+			}
+			out.WriteByte(':')
+			if err := encodeJSON(out, pair.Value, visiting); err != nil {
+				return err
+			}
+		}
+		out.WriteByte('}')
+	default:
+		return newError("cannot convert %s to JSON", value.Type())
+	}
+	return nil
+}
+
+func quoteJSON(s string) string {
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(out.String(), "\n")
+}
@@ -0,0 +1,243 @@
+package representation
+
+import (
+	"fmt"
+	"math"
+)
+
+// numberArgument returns the argument of a builtin at index, which must be an
+// integer or a float.
+func numberArgument(name string, args []Representation, index int) (Representation, *Error) {
+	if !IsNumber(args[index]) {
+		if len(args) == 1 {
+			return nil, newError("argument to `%s` must be a number, got %s", name, args[index].Type())
+		}
+		return nil, newError("argument %d to `%s` must be a number, got %s", index+1, name, args[index].Type())
+	}
+	return args[index], nil
+}
+
+// floatArguments returns the arguments of a builtin that takes exactly count
+// numbers, as floats.
+func floatArguments(name string, args []Representation, count int) ([]float64, *Error) {
+	if err := checkArity(args, count, count); err != nil {
+		return nil, err
+	}
+	floats := make([]float64, count)
+	for i := range args {
+		if _, err := numberArgument(name, args, i); err != nil {
+			return nil, err
+		}
+		floats[i] = toFloat(args[i])
+	}
+	return floats, nil
+}
+
+// floatFunction makes a builtin from a function of one float.
+func floatFunction(name string, fn func(float64) float64) BuiltinFunction {
+	return func(host *Host, args ...Representation) Representation {
+		x, err := floatArguments(name, args, 1)
+		if err != nil {
+			return err
+		}
+		return &Float{Value: fn(x[0])}
+	}
+}
+
+// toInteger converts a float with no fraction to an integer, failing when it
+// is out of range or not a number.
+func toInteger(name string, f float64) Representation {
+	if math.IsNaN(f) || math.IsInf(f, 0) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return newError("`%s`: %s does not fit in an integer", name, (&Float{Value: f}).Inspect())
+	}
+	return &Integer{Value: int64(f)}
+}
+
+// roundingFunction makes a builtin that rounds a number to an integer.
+// Integers are returned unchanged.
+func roundingFunction(name string, fn func(float64) float64) BuiltinFunction {
+	return func(host *Host, args ...Representation) Representation {
+		if err := checkArity(args, 1, 1); err != nil {
+			return err
+		}
+		switch arg := args[0].(type) {
+		case *Integer:
+			return arg
+		case *Float:
+			return toInteger(name, fn(arg.Value))
+		default:
+			return newError("argument to `%s` must be a number, got %s", name, args[0].Type())
+		}
+	}
+}
+
+func builtinAbs(host *Host, args ...Representation) Representation {
+	if err := checkArity(args, 1, 1); err != nil {
+		return err
+	}
+	switch arg := args[0].(type) {
+	case *Integer:
+		if arg.Value >= 0 {
+			return arg
+		}
+		result, err := Negate(arg)
+		if err != nil {
+			return newError("%s", err)
+		}
+		return result
+	case *Float:
+		return &Float{Value: math.Abs(arg.Value)}
+	default:
+		return newError("argument to `abs` must be a number, got %s", args[0].Type())
+	}
+}
+
+func builtinMin(host *Host, args ...Representation) Representation {
+	return extreme("min", args, -1)
+}
+
+func builtinMax(host *Host, args ...Representation) Representation {
+	return extreme("max", args, 1)
+}
+
+// extreme returns the number that compares as sign against all others, taken
+// either from the arguments or from a single array argument. The first one
+// wins ties, and NaN wins over everything.
+func extreme(name string, args []Representation, sign int) Representation {
+	if len(args) == 1 {
+		if arr, ok := args[0].(*Array); ok {
+			if len(arr.Elements) == 0 {
+				return newError("`%s` of an empty array", name)
+			}
+			args = arr.Elements
+		}
+	}
+	if len(args) == 0 {
+		return newError("wrong number of arguments, got=0, want=at least 1")
+	}
+
+	var best Representation
+	for i := range args {
+		if !IsNumber(args[i]) {
+			return newError("`%s` needs numbers, got %s", name, args[i].Type())
+		}
+		switch {
+		case best == nil:
+			best = args[i]
+		case isNaN(best):
+		case isNaN(args[i]) || compareNumbers(args[i], best) == sign:
+			best = args[i]
+		}
+	}
+	return best
+}
+
+func builtinPow(host *Host, args ...Representation) Representation {
+	x, err := floatArguments("pow", args, 2)
+	if err != nil {
+		return err
+	}
+
+	base, baseInt := args[0].(*Integer)
+	exponent, exponentInt := args[1].(*Integer)
+	if !baseInt || !exponentInt || exponent.Value < 0 {
+		return &Float{Value: math.Pow(x[0], x[1])}
+	}
+
+	result, powErr := integerPow(base.Value, exponent.Value)
+	if powErr != nil {
+		return newError("%s", powErr)
+	}
+	return &Integer{Value: result}
+}
+
+// integerPow raises base to a non-negative exponent by squaring, failing
+// when the result does not fit in 64 bits.
+func integerPow(base int64, exponent int64) (int64, error) {
+	result, square := int64(1), base
+	for e := exponent; e > 0; e >>= 1 {
+		var err error
+		if e&1 == 1 {
+			if result, err = integerArithmetic("*", result, square); err != nil {
+				return 0, fmt.Errorf("integer overflow: pow(%d, %d)", base, exponent)
+			}
+		}
+		if e > 1 {
+			if square, err = integerArithmetic("*", square, square); err != nil {
+				return 0, fmt.Errorf("integer overflow: pow(%d, %d)", base, exponent)
+			}
+		}
+	}
+	return result, nil
+}
+
+func builtinSqrt(host *Host, args ...Representation) Representation {
+	x, err := floatArguments("sqrt", args, 1)
+	if err != nil {
+		return err
+	}
+	if x[0] < 0 {
+		return newError("argument to `sqrt` must not be negative, got %s", args[0].Inspect())
+	}
+	return &Float{Value: math.Sqrt(x[0])}
+}
+
+func builtinClamp(host *Host, args ...Representation) Representation {
+	if err := checkArity(args, 3, 3); err != nil {
+		return err
+	}
+	for i := range args {
+		if _, err := numberArgument("clamp", args, i); err != nil {
+			return err
+		}
+	}
+
+	value, low, high := args[0], args[1], args[2]
+	if compareNumbers(low, high) > 0 {
+		return newError("`clamp`: lower bound %s is above upper bound %s", low.Inspect(), high.Inspect())
+	}
+	switch {
+	case compareNumbers(value, low) < 0:
+		return low
+	case compareNumbers(value, high) > 0:
+		return high
+	default:
+		return value
+	}
+}
+
+// builtinAtan takes atan(x), or atan(y, x) for the angle of the point (x, y)
+// in all four quadrants.
+func builtinAtan(host *Host, args ...Representation) Representation {
+	if err := checkArity(args, 1, 2); err != nil {
+		return err
+	}
+	x, err := floatArguments("atan", args, len(args))
+	if err != nil {
+		return err
+	}
+	if len(x) == 1 {
+		return &Float{Value: math.Atan(x[0])}
+	}
+	return &Float{Value: math.Atan2(x[0], x[1])}
+}
+
+func builtinLog(host *Host, args ...Representation) Representation {
+	if err := checkArity(args, 1, 2); err != nil {
+		return err
+	}
+	x, err := floatArguments("log", args, len(args))
+	if err != nil {
+		return err
+	}
+	if x[0] <= 0 {
+		return newError("argument to `log` must be positive, got %s", args[0].Inspect())
+	}
+	if len(x) == 1 {
+		return &Float{Value: math.Log(x[0])}
+	}
+	if x[1] <= 0 || x[1] == 1 {
+		return newError("`log`: invalid base %s", args[1].Inspect())
+	}
+	return &Float{Value: math.Log(x[0]) / math.Log(x[1])}
+}
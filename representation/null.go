@@ -4,3 +4,6 @@ type Null struct{}
 
 func (n *Null) Inspect() string  { return "null" }
 func (n *Null) Type() RepresentationType { return NULL_REPR }
+
+// NULL is the only null value.
+var NULL = &Null{}
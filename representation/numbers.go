@@ -0,0 +1,127 @@
+package representation
+
+import (
+	"fmt"
+	"math"
+)
+
+// IsNumber reports whether value is an integer or a float.
+func IsNumber(value Representation) bool {
+	switch value.(type) {
+	case *Integer, *Float:
+		return true
+	default:
+		return false
+	}
+}
+
+// toFloat returns the value of an integer or a float as a float.
+func toFloat(value Representation) float64 {
+	if n, ok := value.(*Integer); ok {
+		return float64(n.Value)
+	}
+	return value.(*Float).Value
+}
+
+// Arithmetic applies +, -, * or / to two numbers. Two integers give an
+// integer, and results that do not fit in 64 bits are errors rather than
+// wrapping around. A float on either side makes the result a float.
+func Arithmetic(operator string, left Representation, right Representation) (Representation, error) {
+	l, leftInt := left.(*Integer)
+	r, rightInt := right.(*Integer)
+	if leftInt && rightInt {
+		result, err := integerArithmetic(operator, l.Value, r.Value)
+		if err != nil {
+			return nil, err
+		}
+		return &Integer{Value: result}, nil
+	}
+
+	a, b := toFloat(left), toFloat(right)
+	switch operator {
+	case "+":
+		return &Float{Value: a + b}, nil
+	case "-":
+		return &Float{Value: a - b}, nil
+	case "*":
+		return &Float{Value: a * b}, nil
+	case "/":
+		if b == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return &Float{Value: a / b}, nil
+	default:
+		return nil, fmt.Errorf("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+func integerArithmetic(operator string, a int64, b int64) (int64, error) {
+	var result int64
+	overflow := false
+
+	switch operator {
+	case "+":
+		result = a + b
+		overflow = (b > 0 && result < a) || (b < 0 && result > a)
+	case "-":
+		result = a - b
+		overflow = (b < 0 && result < a) || (b > 0 && result > a)
+	case "*":
+		result = a * b
+		overflow = a != 0 && (result/a != b || (a == -1 && b == math.MinInt64))
+	case "/":
+		if b == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+		overflow = a == math.MinInt64 && b == -1
+		result = a / b
+	default:
+		return 0, fmt.Errorf("unknown operator: INTEGER %s INTEGER", operator)
+	}
+
+	if overflow {
+		return 0, fmt.Errorf("integer overflow: %d %s %d", a, operator, b)
+	}
+	return result, nil
+}
+
+// Negate returns the negative of a number.
+func Negate(value Representation) (Representation, error) {
+	switch value := value.(type) {
+	case *Integer:
+		if value.Value == math.MinInt64 {
+			return nil, fmt.Errorf("integer overflow: -(%d)", value.Value)
+		}
+		return &Integer{Value: -value.Value}, nil
+	case *Float:
+		return &Float{Value: -value.Value}, nil
+	default:
+		return nil, fmt.Errorf("unknown operator: -%s", value.Type())
+	}
+}
+
+// compareNumbers orders two numbers, comparing integers exactly.
+func compareNumbers(left Representation, right Representation) int {
+	l, leftInt := left.(*Integer)
+	r, rightInt := right.(*Integer)
+	if leftInt && rightInt {
+		switch {
+		case l.Value < r.Value:
+			return -1
+		case l.Value > r.Value:
+			return 1
+		default:
+			return 0
+		}
+	}
+
+	a, b := toFloat(left), toFloat(right)
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
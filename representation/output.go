@@ -0,0 +1,176 @@
+package representation
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func builtinOut(host *Host, args ...Representation) Representation {
+	for _, arg := range args {
+		fmt.Fprintln(host.output(), arg.Inspect())
+	}
+	return nil
+}
+
+func builtinPrint(host *Host, args ...Representation) Representation {
+	for _, arg := range args {
+		io.WriteString(host.output(), arg.Inspect())
+	}
+	return nil
+}
+
+func builtinFormat(host *Host, args ...Representation) Representation {
+	return formatArguments("format", args)
+}
+
+func builtinPrintf(host *Host, args ...Representation) Representation {
+	result := formatArguments("printf", args)
+	s, ok := result.(*String)
+	if !ok {
+		return result
+	}
+	io.WriteString(host.output(), s.Value)
+	return nil
+}
+
+func builtinInput(host *Host, args ...Representation) Representation {
+	if err := checkArity(args, 0, 1); err != nil {
+		return err
+	}
+	if len(args) == 1 {
+		io.WriteString(host.output(), args[0].Inspect())
+	}
+	return host.readLine()
+}
+
+func builtinReadLine(host *Host, args ...Representation) Representation {
+	if err := checkArity(args, 0, 0); err != nil {
+		return err
+	}
+	return host.readLine()
+}
+
+// output returns the writer builtins print to.
+func (h *Host) output() io.Writer {
+	if h.Stdout == nil {
+		return os.Stdout
+	}
+	return h.Stdout
+}
+
+// readLine reads a line from Stdin without its line ending, returning nil
+// once the input is exhausted.
+func (h *Host) readLine() Representation {
+	if h.Stdin == nil {
+		return nil
+	}
+	if h.input == nil || h.inputSource != h.Stdin {
+		h.input = bufio.NewReader(h.Stdin)
+		h.inputSource = h.Stdin
+	}
+
+	line, err := h.input.ReadString('\n')
+	if err != nil && line == "" {
+		if err == io.EOF {
+			return nil
+		}
+		return newError("reading input: %s", err)
+	}
+	line = strings.TrimSuffix(line, "\n")
+	return &String{Value: strings.TrimSuffix(line, "\r")}
+}
+
+// formatArguments formats the values after the format string of `format`
+// and `printf`. Verbs take the flags, width and precision of Go's fmt:
+//
+//	%v  any value as printed by out, %s a string, %q a quoted value
+//	%d  an integer, %b %o %x %X the same in base 2, 8 or 16
+//	%t  a boolean, %%  a percent sign
+func formatArguments(name string, args []Representation) Representation {
+	if len(args) == 0 {
+		return newError("wrong number of arguments, got=0, want=1 or more")
+	}
+	format, err := stringArgument(name, args, 0)
+	if err != nil {
+		return err
+	}
+	values := args[1:]
+
+	var out strings.Builder
+	next := 0
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			out.WriteByte(format[i])
+			continue
+		}
+
+		end := i + 1
+		for end < len(format) && strings.IndexByte("+-# 0123456789.", format[end]) >= 0 {
+			end++
+		}
+		if end == len(format) {
+			return newError("%s: unterminated verb %q", name, format[i:])
+		}
+		spec, verb := format[i:end], format[end]
+		i = end
+
+		if verb == '%' {
+			out.WriteByte('%')
+			continue
+		}
+		if next == len(values) {
+			return newError("%s: missing argument for %%%c", name, verb)
+		}
+		value := values[next]
+		next++
+
+		formatted, err := formatValue(name, spec, verb, value)
+		if err != nil {
+			return err
+		}
+		out.WriteString(formatted)
+	}
+
+	if next < len(values) {
+		return newError("%s: %d arguments left over", name, len(values)-next)
+	}
+	return &String{Value: out.String()}
+}
+
+func formatValue(name string, spec string, verb byte, value Representation) (string, *Error) {
+	goVerb := spec + string(verb)
+
+	switch verb {
+	case 'v', 's':
+		return fmt.Sprintf(spec+"s", value.Inspect()), nil
+	case 'q':
+		if s, ok := value.(*String); ok {
+			return fmt.Sprintf(goVerb, s.Value), nil
+		}
+		return fmt.Sprintf(spec+"s", strconv.Quote(value.Inspect())), nil
+	case 'd', 'b', 'o', 'x', 'X':
+		if n, ok := value.(*Integer); ok {
+			return fmt.Sprintf(goVerb, n.Value), nil
+		}
+		if s, ok := value.(*String); ok && verb != 'd' {
+			return fmt.Sprintf(goVerb, s.Value), nil
+		}
+		return "", newError("%s: %%%c needs an integer, got %s", name, verb, value.Type())
+	case 'f', 'F', 'e', 'E', 'g', 'G':
+		if IsNumber(value) {
+			return fmt.Sprintf(goVerb, toFloat(value)), nil
+		}
+		return "", newError("%s: %%%c needs a number, got %s", name, verb, value.Type())
+	case 't':
+		if b, ok := value.(*Boolean); ok {
+			return fmt.Sprintf(goVerb, b.Value), nil
+		}
+		return "", newError("%s: %%t needs a boolean, got %s", name, value.Type())
+	default:
+		return "", newError("%s: unknown verb %%%c", name, verb)
+	}
+}
@@ -0,0 +1,84 @@
+package representation
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// random returns the generator of the random builtins, seeding one from the
+// clock the first time a host without Rand needs it.
+func (h *Host) random() *rand.Rand {
+	if h.Rand == nil {
+		h.Rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return h.Rand
+}
+
+func builtinRandom(host *Host, args ...Representation) Representation {
+	if err := checkArity(args, 0, 0); err != nil {
+		return err
+	}
+	return &Float{Value: host.random().Float64()}
+}
+
+func builtinRandomInt(host *Host, args ...Representation) Representation {
+	if err := checkArity(args, 2, 2); err != nil {
+		return err
+	}
+	low, err := integerArgument("random_int", args, 0)
+	if err != nil {
+		return err
+	}
+	high, err := integerArgument("random_int", args, 1)
+	if err != nil {
+		return err
+	}
+	if low > high {
+		return newError("`random_int`: lower bound %d is above upper bound %d", low, high)
+	}
+
+	// The span is computed in unsigned arithmetic so that it cannot overflow,
+	// and values beyond it are drawn again to keep the distribution uniform.
+	r := host.random()
+	span := uint64(high) - uint64(low)
+	if span < math.MaxInt64 {
+		return &Integer{Value: low + r.Int63n(int64(span)+1)}
+	}
+	for {
+		if n := r.Uint64(); n <= span {
+			return &Integer{Value: low + int64(n)}
+		}
+	}
+}
+
+func builtinShuffle(host *Host, args ...Representation) Representation {
+	if err := checkArity(args, 1, 1); err != nil {
+		return err
+	}
+	arr, err := arrayArgument("shuffle", args, 0)
+	if err != nil {
+		return err
+	}
+
+	elements := make([]Representation, len(arr.Elements))
+	copy(elements, arr.Elements)
+	host.random().Shuffle(len(elements), func(i int, j int) {
+		elements[i], elements[j] = elements[j], elements[i]
+	})
+	return &Array{Elements: elements}
+}
+
+func builtinChoice(host *Host, args ...Representation) Representation {
+	if err := checkArity(args, 1, 1); err != nil {
+		return err
+	}
+	arr, err := arrayArgument("choice", args, 0)
+	if err != nil {
+		return err
+	}
+	if len(arr.Elements) == 0 {
+		return newError("`choice` of an empty array")
+	}
+	return arr.Elements[host.random().Intn(len(arr.Elements))]
+}
@@ -0,0 +1,186 @@
+package representation
+
+import (
+	"errors"
+	"regexp"
+	"regexp/syntax"
+)
+
+// maxCachedPatterns bounds the compiled patterns a host keeps. When it is
+// reached the cache starts over, which only costs recompiling.
+const maxCachedPatterns = 128
+
+// pattern returns the compiled form of the argument of a builtin at index,
+// from the host's cache when the pattern was used before.
+func (h *Host) pattern(name string, args []Representation, index int) (*regexp.Regexp, *Error) {
+	source, err := stringArgument(name, args, index)
+	if err != nil {
+		return nil, err
+	}
+	if re, ok := h.patterns[source]; ok {
+		return re, nil
+	}
+
+	re, compileErr := regexp.Compile(source)
+	if compileErr != nil {
+		var syntaxErr *syntax.Error
+		if errors.As(compileErr, &syntaxErr) {
+			return nil, newError("%s: invalid pattern %q: %s", name, source, syntaxErr.Code)
+		}
+		return nil, newError("%s: invalid pattern %q: %s", name, source, compileErr)
+	}
+
+	if h.patterns == nil || len(h.patterns) >= maxCachedPatterns {
+		h.patterns = map[string]*regexp.Regexp{}
+	}
+	h.patterns[source] = re
+	return re, nil
+}
+
+// stringAndPattern returns the string and the pattern a regex builtin takes
+// as its first two arguments.
+func stringAndPattern(host *Host, name string, args []Representation) (string, *regexp.Regexp, *Error) {
+	s, err := stringArgument(name, args, 0)
+	if err != nil {
+		return "", nil, err
+	}
+	re, err := host.pattern(name, args, 1)
+	if err != nil {
+		return "", nil, err
+	}
+	return s, re, nil
+}
+
+// groups makes an array of a match and its capture groups, with null for the
+// groups that did not take part in it.
+func groups(s string, indices []int) *Array {
+	elements := make([]Representation, len(indices)/2)
+	for i := range elements {
+		start, end := indices[2*i], indices[2*i+1]
+		if start < 0 {
+			elements[i] = NULL
+		} else {
+			elements[i] = &String{Value: s[start:end]}
+		}
+	}
+	return &Array{Elements: elements}
+}
+
+func builtinMatch(host *Host, args ...Representation) Representation {
+	if err := checkArity(args, 2, 2); err != nil {
+		return err
+	}
+	s, re, err := stringAndPattern(host, "match", args)
+	if err != nil {
+		return err
+	}
+
+	indices := re.FindStringSubmatchIndex(s)
+	if indices == nil {
+		return NULL
+	}
+	return groups(s, indices)
+}
+
+func builtinCaptures(host *Host, args ...Representation) Representation {
+	if err := checkArity(args, 2, 2); err != nil {
+		return err
+	}
+	s, re, err := stringAndPattern(host, "captures", args)
+	if err != nil {
+		return err
+	}
+
+	indices := re.FindStringSubmatchIndex(s)
+	if indices == nil {
+		return NULL
+	}
+	matched := groups(s, indices)
+	hash := NewHash()
+	for i, name := range re.SubexpNames() {
+		if name == "" {
+			continue
+		}
+		key := &String{Value: name}
+		hash.Set(key.HashKey(), HashPair{Key: key, Value: matched.Elements[i]})
+	}
+	return hash
+}
+
+func builtinFindAll(host *Host, args ...Representation) Representation {
+	if err := checkArity(args, 2, 3); err != nil {
+		return err
+	}
+	s, re, err := stringAndPattern(host, "find_all", args)
+	if err != nil {
+		return err
+	}
+	limit := int64(-1)
+	if len(args) == 3 {
+		if limit, err = integerArgument("find_all", args, 2); err != nil {
+			return err
+		}
+	}
+
+	matches := []Representation{}
+	for _, indices := range re.FindAllStringSubmatchIndex(s, int(limit)) {
+		matches = append(matches, groups(s, indices))
+	}
+	return &Array{Elements: matches}
+}
+
+// builtinReplaceRegex replaces every match with a string, in which $1 or
+// ${name} stand for groups, or with what a function returns for the match
+// and its groups.
+func builtinReplaceRegex(host *Host, args ...Representation) Representation {
+	if err := checkArity(args, 3, 3); err != nil {
+		return err
+	}
+	s, re, err := stringAndPattern(host, "replace_regex", args)
+	if err != nil {
+		return err
+	}
+
+	if replacement, ok := args[2].(*String); ok {
+		return &String{Value: re.ReplaceAllString(s, replacement.Value)}
+	}
+	fn, err := functionArgument("replace_regex", args, 2)
+	if err != nil {
+		return newError("argument 3 to `replace_regex` must be a string or a function, got %s", args[2].Type())
+	}
+
+	var out []byte
+	last := 0
+	for _, indices := range re.FindAllStringSubmatchIndex(s, -1) {
+		result := host.call(fn, groups(s, indices))
+		if Stops(result) {
+			return result
+		}
+		replacement, ok := result.(*String)
+		if !ok {
+			return newError("replace_regex: the function must return a string, got %s", result.Type())
+		}
+		out = append(out, s[last:indices[0]]...)
+		out = append(out, replacement.Value...)
+		last = indices[1]
+	}
+	out = append(out, s[last:]...)
+	return &String{Value: string(out)}
+}
+
+func builtinSplitRegex(host *Host, args ...Representation) Representation {
+	if err := checkArity(args, 2, 3); err != nil {
+		return err
+	}
+	s, re, err := stringAndPattern(host, "split_regex", args)
+	if err != nil {
+		return err
+	}
+	limit := int64(-1)
+	if len(args) == 3 {
+		if limit, err = integerArgument("split_regex", args, 2); err != nil {
+			return err
+		}
+	}
+	return stringArray(re.Split(s, int(limit)))
+}
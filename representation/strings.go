@@ -0,0 +1,241 @@
+package representation
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// maxRepeatLength bounds the strings `repeat` builds, so that a huge count is
+// reported rather than exhausting memory.
+const maxRepeatLength = 1 << 30
+
+func builtinSplit(host *Host, args ...Representation) Representation {
+	if err := checkArity(args, 1, 2); err != nil {
+		return err
+	}
+	s, err := stringArgument("split", args, 0)
+	if err != nil {
+		return err
+	}
+
+	var parts []string
+	if len(args) == 1 {
+		parts = strings.Fields(s)
+	} else {
+		sep, err := stringArgument("split", args, 1)
+		if err != nil {
+			return err
+		}
+		parts = strings.Split(s, sep)
+	}
+
+	return stringArray(parts)
+}
+
+func builtinJoin(host *Host, args ...Representation) Representation {
+	if err := checkArity(args, 1, 2); err != nil {
+		return err
+	}
+	arr, ok := args[0].(*Array)
+	if !ok {
+		return newError("argument 1 to `join` must be an array, got %s", args[0].Type())
+	}
+	sep := ""
+	if len(args) == 2 {
+		var err *Error
+		if sep, err = stringArgument("join", args, 1); err != nil {
+			return err
+		}
+	}
+
+	parts := make([]string, len(arr.Elements))
+	for i, element := range arr.Elements {
+		s, ok := element.(*String)
+		if !ok {
+			return newError("`join` needs an array of strings, got %s at index %d", element.Type(), i)
+		}
+		parts[i] = s.Value
+	}
+
+	return &String{Value: strings.Join(parts, sep)}
+}
+
+func builtinTrim(host *Host, args ...Representation) Representation {
+	if err := checkArity(args, 1, 2); err != nil {
+		return err
+	}
+	s, err := stringArgument("trim", args, 0)
+	if err != nil {
+		return err
+	}
+	if len(args) == 1 {
+		return &String{Value: strings.TrimSpace(s)}
+	}
+
+	cutset, err := stringArgument("trim", args, 1)
+	if err != nil {
+		return err
+	}
+	return &String{Value: strings.Trim(s, cutset)}
+}
+
+func builtinUpper(host *Host, args ...Representation) Representation {
+	return mapString("upper", strings.ToUpper, args)
+}
+
+func builtinLower(host *Host, args ...Representation) Representation {
+	return mapString("lower", strings.ToLower, args)
+}
+
+func builtinContains(host *Host, args ...Representation) Representation {
+	if len(args) == 2 {
+		if arr, ok := args[0].(*Array); ok {
+			return NativeBool(arrayIndexOf(arr, args[1]) >= 0)
+		}
+	}
+	return testStrings("contains", strings.Contains, args)
+}
+
+func builtinStartsWith(host *Host, args ...Representation) Representation {
+	return testStrings("starts_with", strings.HasPrefix, args)
+}
+
+func builtinEndsWith(host *Host, args ...Representation) Representation {
+	return testStrings("ends_with", strings.HasSuffix, args)
+}
+
+func builtinReplace(host *Host, args ...Representation) Representation {
+	if err := checkArity(args, 3, 4); err != nil {
+		return err
+	}
+	strs, err := stringArguments("replace", args[:3])
+	if err != nil {
+		return err
+	}
+
+	count := -1
+	if len(args) == 4 {
+		n, ok := args[3].(*Integer)
+		if !ok {
+			return newError("argument 4 to `replace` must be an integer, got %s", args[3].Type())
+		}
+		count = int(n.Value)
+	}
+
+	return &String{Value: strings.Replace(strs[0], strs[1], strs[2], count)}
+}
+
+func builtinIndexOf(host *Host, args ...Representation) Representation {
+	if err := checkArity(args, 2, 2); err != nil {
+		return err
+	}
+	if arr, ok := args[0].(*Array); ok {
+		return &Integer{Value: int64(arrayIndexOf(arr, args[1]))}
+	}
+	strs, err := stringArguments("index_of", args)
+	if err != nil {
+		return err
+	}
+
+	at := strings.Index(strs[0], strs[1])
+	if at < 0 {
+		return &Integer{Value: -1}
+	}
+	return &Integer{Value: int64(utf8.RuneCountInString(strs[0][:at]))}
+}
+
+func builtinSubstring(host *Host, args ...Representation) Representation {
+	if err := checkArity(args, 2, 3); err != nil {
+		return err
+	}
+	s, err := stringArgument("substring", args, 0)
+	if err != nil {
+		return err
+	}
+
+	runes := []rune(s)
+	bounds := []int64{0, int64(len(runes))}
+	for i, arg := range args[1:] {
+		n, ok := arg.(*Integer)
+		if !ok {
+			return newError("argument %d to `substring` must be an integer, got %s", i+2, arg.Type())
+		}
+		bounds[i] = n.Value
+	}
+
+	start, end := bounds[0], bounds[1]
+	if start < 0 || end > int64(len(runes)) || start > end {
+		return newError("substring bounds out of range: [%d:%d] with length %d", start, end, len(runes))
+	}
+	return &String{Value: string(runes[start:end])}
+}
+
+func builtinRepeat(host *Host, args ...Representation) Representation {
+	if err := checkArity(args, 2, 2); err != nil {
+		return err
+	}
+	s, err := stringArgument("repeat", args, 0)
+	if err != nil {
+		return err
+	}
+	count, ok := args[1].(*Integer)
+	if !ok {
+		return newError("argument 2 to `repeat` must be an integer, got %s", args[1].Type())
+	}
+	if count.Value < 0 {
+		return newError("negative count to `repeat`: %d", count.Value)
+	}
+	if len(s) > 0 && count.Value > maxRepeatLength/int64(len(s)) {
+		return newError("`repeat`: result would be longer than %d bytes", maxRepeatLength)
+	}
+
+	return &String{Value: strings.Repeat(s, int(count.Value))}
+}
+
+func builtinChars(host *Host, args ...Representation) Representation {
+	if err := checkArity(args, 1, 1); err != nil {
+		return err
+	}
+	s, err := stringArgument("chars", args, 0)
+	if err != nil {
+		return err
+	}
+
+	chars := []string{}
+	for _, r := range s {
+		chars = append(chars, string(r))
+	}
+	return stringArray(chars)
+}
+
+// mapString applies a function to the single string argument of a builtin.
+func mapString(name string, fn func(string) string, args []Representation) Representation {
+	if err := checkArity(args, 1, 1); err != nil {
+		return err
+	}
+	s, err := stringArgument(name, args, 0)
+	if err != nil {
+		return err
+	}
+	return &String{Value: fn(s)}
+}
+
+// testStrings applies a predicate to the two string arguments of a builtin.
+func testStrings(name string, fn func(string, string) bool, args []Representation) Representation {
+	if err := checkArity(args, 2, 2); err != nil {
+		return err
+	}
+	strs, err := stringArguments(name, args)
+	if err != nil {
+		return err
+	}
+	return NativeBool(fn(strs[0], strs[1]))
+}
+
+func stringArray(strs []string) *Array {
+	elements := make([]Representation, len(strs))
+	for i, s := range strs {
+		elements[i] = &String{Value: s}
+	}
+	return &Array{Elements: elements}
+}
@@ -4,6 +4,7 @@ type RepresentationType string
 
 const (
 	INTEGER_REPR           RepresentationType = "INTEGER"
+	FLOAT_REPR             RepresentationType = "FLOAT"
 	BOOLEAN_REPR           RepresentationType = "BOOLEAN"
 	NULL_REPR              RepresentationType = "NULL"
 	RETURN_VALUE_REPR      RepresentationType = "RETURN_VALUE"
@@ -15,6 +16,9 @@ const (
 	HASH_REPR              RepresentationType = "HASH"
 	COMPILED_FUNCTION_REPR RepresentationType = "COMPILED_FUNCTION"
 	CLOSURE_REPR           RepresentationType = "CLOSURE"
+	EXIT_REPR              RepresentationType = "EXIT"
+	FAILURE_REPR           RepresentationType = "FAILURE"
+	HALT_REPR              RepresentationType = "HALT"
 )
 
 type Representation interface {
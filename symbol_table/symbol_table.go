@@ -89,3 +89,16 @@ func (st *SymbolTable) DefineFunctionName(name string) Symbol {
 	st.Store[name] = symbol
 	return symbol
 }
+
+// Clone returns a copy of the table that can be defined into without
+// affecting the original. Outer tables are shared, not copied.
+func (st *SymbolTable) Clone() *SymbolTable {
+	clone := NewSymbolTable()
+	clone.Outer = st.Outer
+	clone.NumDefinitions = st.NumDefinitions
+	clone.FreeSymbols = append(clone.FreeSymbols, st.FreeSymbols...)
+	for name, symbol := range st.Store {
+		clone.Store[name] = symbol
+	}
+	return clone
+}
@@ -295,3 +295,21 @@ func TestShadowingFunctionName(t *testing.T) {
 			expected.Name, expected, result)
 	}
 }
+
+func TestClone(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+
+	clone := global.Clone()
+	b := clone.Define("b")
+	if b.Index != 1 {
+		t.Errorf("clone did not continue numbering. got=%d, want=1", b.Index)
+	}
+
+	if _, ok := global.Resolve("b"); ok {
+		t.Errorf("defining in the clone changed the original")
+	}
+	if _, ok := clone.Resolve("a"); !ok {
+		t.Errorf("clone lost existing definitions")
+	}
+}
@@ -1,18 +1,27 @@
 package token
 
+import "sort"
+
 type TokenType string
 
 type Token struct {
 	Type    TokenType
 	Literal string
+
+	// Line and Column locate the first character of the token, both counting
+	// from 1. Columns count bytes.
+	Line   int
+	Column int
 }
 
 const (
 	ILLEGAL = "ILLEGAL"
 	EOF     = "EOF"
+	COMMENT = "COMMENT"
 
 	IDENTIFER = "IDENTIFER"
 	INT       = "INT"
+	FLOAT     = "FLOAT"
 	STRING    = "STRING"
 
 	ASSIGN   = "="
@@ -62,3 +71,13 @@ func LookupIdentifier(identifier string) TokenType {
 	}
 	return IDENTIFER
 }
+
+// Keywords returns the reserved words of the language in sorted order.
+func Keywords() []string {
+	words := make([]string, 0, len(keywords))
+	for word := range keywords {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	return words
+}
@@ -1,6 +1,7 @@
 package vm
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/mislavperi/adl-lang/code"
@@ -12,9 +13,9 @@ const GlobalsSize = 65536
 const StackSize = 2048
 const MaxFrames = 1024
 
-var True = &representation.Boolean{Value: true}
-var False = &representation.Boolean{Value: false}
-var Null = &representation.Null{}
+var True = representation.TRUE
+var False = representation.FALSE
+var Null = representation.NULL
 
 type VM struct {
 	constants []representation.Representation
@@ -26,6 +27,8 @@ type VM struct {
 
 	frames      []*Frame
 	framesIndex int
+
+	host *representation.Host
 }
 
 func New(bytecode *compiler.Bytecode) *VM {
@@ -36,7 +39,7 @@ func New(bytecode *compiler.Bytecode) *VM {
 	frames := make([]*Frame, MaxFrames)
 	frames[0] = mainFrame
 
-	return &VM{
+	vm := &VM{
 		constants: bytecode.Constants,
 
 		globals: make([]representation.Representation, GlobalsSize),
@@ -47,6 +50,8 @@ func New(bytecode *compiler.Bytecode) *VM {
 		frames:      frames,
 		framesIndex: 1,
 	}
+	vm.SetHost(representation.NewHost())
+	return vm
 }
 
 func NewWithGlobalStore(bytecode *compiler.Bytecode, s []representation.Representation) *VM {
@@ -55,12 +60,25 @@ func NewWithGlobalStore(bytecode *compiler.Bytecode, s []representation.Represen
 	return vm
 }
 
+// SetHost replaces the host builtins are called with, and lets them call
+// functions on this VM.
+func (vm *VM) SetHost(host *representation.Host) {
+	host.Call = vm.call
+	vm.host = host
+}
+
 func (vm *VM) Run() error {
+	return vm.run(0)
+}
+
+// run executes instructions until the main frame ends or, when a builtin
+// calls back into the program, until the frames above depth have returned.
+func (vm *VM) run(depth int) error {
 	var instructonPointer int
 	var ins code.Instructions
 	var op code.Opcode
 
-	for vm.currentFrame().instructonPointer < len(vm.currentFrame().Instructions())-1 {
+	for vm.framesIndex > depth && vm.currentFrame().instructonPointer < len(vm.currentFrame().Instructions())-1 {
 		vm.currentFrame().instructonPointer++
 
 		instructonPointer = vm.currentFrame().instructonPointer
@@ -158,9 +176,27 @@ func (vm *VM) Run() error {
 			if err := vm.executeIndexExpression(left, index); err != nil {
 				return err
 			}
+		case code.OpSlice:
+			end := vm.pop()
+			start := vm.pop()
+			left := vm.pop()
+
+			result, err := vm.host.Slice(left, start, end)
+			if err != nil {
+				return fmt.Errorf("%s", err.Message)
+			}
+			if err := vm.push(result); err != nil {
+				return err
+			}
 		case code.OpReturnValue:
 			returnValue := vm.pop()
 
+			if vm.framesIndex == 1 {
+				// A return at the top level ends the program with its value.
+				vm.stack[vm.stackPointer] = returnValue
+				return nil
+			}
+
 			frame := vm.popFrame()
 			vm.stackPointer = frame.basePointer - 1
 
@@ -174,25 +210,25 @@ func (vm *VM) Run() error {
 			if err := vm.push(Null); err != nil {
 				return err
 			}
-		case code.OpCall:
-			numArgs := code.ReadUint8(ins[instructonPointer+1:])
-			vm.currentFrame().instructonPointer += 1
+		case code.OpCall, code.OpCallWide:
+			numArgs := vm.readIndexOperand(op, ins, instructonPointer)
 
-			if err := vm.executeCall(int(numArgs)); err != nil {
+			if err := vm.host.Err(); err != nil {
 				return err
 			}
-		case code.OpSetLocal:
-			localIndex := code.ReadUint8(ins[instructonPointer+1:])
-			vm.currentFrame().instructonPointer += 1
+			if err := vm.executeCall(numArgs); err != nil {
+				return err
+			}
+		case code.OpSetLocal, code.OpSetLocalWide:
+			localIndex := vm.readIndexOperand(op, ins, instructonPointer)
 
 			frame := vm.currentFrame()
-			vm.stack[frame.basePointer+int(localIndex)] = vm.pop()
-		case code.OpGetLocal:
-			localIndex := code.ReadUint8(ins[instructonPointer+1:])
-			vm.currentFrame().instructonPointer += 1
+			vm.stack[frame.basePointer+localIndex] = vm.pop()
+		case code.OpGetLocal, code.OpGetLocalWide:
+			localIndex := vm.readIndexOperand(op, ins, instructonPointer)
 
 			frame := vm.currentFrame()
-			if err := vm.push(vm.stack[frame.basePointer+int(localIndex)]); err != nil {
+			if err := vm.push(vm.stack[frame.basePointer+localIndex]); err != nil {
 
 				return err
 			}
@@ -202,7 +238,12 @@ func (vm *VM) Run() error {
 
 			definition := representation.Builtins[builtinIndex]
 
-			err := vm.push(definition.Builtin)
+			var value representation.Representation = definition.Builtin
+			if definition.Builtin.Value != nil {
+				value = definition.Builtin.Value
+			}
+
+			err := vm.push(value)
 			if err != nil {
 				return err
 			}
@@ -214,10 +255,17 @@ func (vm *VM) Run() error {
 			if err := vm.pushClosure(int(constIndex), int(numFree)); err != nil {
 				return err
 			}
+		case code.OpClosureWide:
+			constIndex := code.ReadUint16(ins[instructonPointer+1:])
+			numFree := code.ReadUint16(ins[instructonPointer+3:])
+			vm.currentFrame().instructonPointer += 4
 
-		case code.OpGetFree:
-			freeIndex := code.ReadUint8(ins[instructonPointer+1:])
-			vm.currentFrame().instructonPointer += 1
+			if err := vm.pushClosure(int(constIndex), int(numFree)); err != nil {
+				return err
+			}
+
+		case code.OpGetFree, code.OpGetFreeWide:
+			freeIndex := vm.readIndexOperand(op, ins, instructonPointer)
 
 			currentClosure := vm.currentFrame().closure
 			err := vm.push(currentClosure.Free[freeIndex])
@@ -237,6 +285,19 @@ func (vm *VM) Run() error {
 	return nil
 }
 
+// readIndexOperand reads the single operand of an opcode that comes in a
+// one-byte and a wide two-byte form, and advances past it.
+func (vm *VM) readIndexOperand(op code.Opcode, ins code.Instructions, instructonPointer int) int {
+	switch op {
+	case code.OpGetLocalWide, code.OpSetLocalWide, code.OpGetFreeWide, code.OpCallWide:
+		vm.currentFrame().instructonPointer += 2
+		return int(code.ReadUint16(ins[instructonPointer+1:]))
+	default:
+		vm.currentFrame().instructonPointer += 1
+		return int(code.ReadUint8(ins[instructonPointer+1:]))
+	}
+}
+
 func (vm *VM) LastPoppedStackElem() representation.Representation {
 	return vm.stack[vm.stackPointer]
 }
@@ -267,8 +328,8 @@ func (vm *VM) executeBinaryOperation(op code.Opcode) error {
 
 	switch {
 
-	case leftType == representation.INTEGER_REPR && right.Type() == representation.INTEGER_REPR:
-		return vm.executeBinaryIntegerOperation(left, op, right)
+	case representation.IsNumber(left) && representation.IsNumber(right):
+		return vm.executeBinaryNumberOperation(left, op, right)
 
 	case leftType == representation.STRING_REPR && right.Type() == representation.STRING_REPR:
 		return vm.executeBinaryStringOperation(left, op, right)
@@ -277,26 +338,24 @@ func (vm *VM) executeBinaryOperation(op code.Opcode) error {
 	}
 }
 
-func (vm *VM) executeBinaryIntegerOperation(left representation.Representation, operator code.Opcode, right representation.Representation) error {
-	leftValue := left.(*representation.Integer).Value
-	rightValue := right.(*representation.Integer).Value
-
-	var result int64
+var arithmeticOperators = map[code.Opcode]string{
+	code.OpAdd: "+",
+	code.OpSub: "-",
+	code.OpMul: "*",
+	code.OpDiv: "/",
+}
 
-	switch operator {
-	case code.OpAdd:
-		result = leftValue + rightValue
-	case code.OpSub:
-		result = leftValue - rightValue
-	case code.OpMul:
-		result = leftValue * rightValue
-	case code.OpDiv:
-		result = leftValue / rightValue
-	default:
-		return fmt.Errorf("unknown integer operator: %d", operator)
+func (vm *VM) executeBinaryNumberOperation(left representation.Representation, operator code.Opcode, right representation.Representation) error {
+	symbol, ok := arithmeticOperators[operator]
+	if !ok {
+		return fmt.Errorf("unknown number operator: %d", operator)
 	}
 
-	return vm.push(&representation.Integer{Value: result})
+	result, err := representation.Arithmetic(symbol, left, right)
+	if err != nil {
+		return err
+	}
+	return vm.push(result)
 }
 
 func (vm *VM) executeBinaryStringOperation(left representation.Representation, operator code.Opcode, right representation.Representation) error {
@@ -319,14 +378,23 @@ func (vm *VM) executeComparison(op code.Opcode) error {
 	right := vm.pop()
 	left := vm.pop()
 
-	if left.Type() == representation.INTEGER_REPR && right.Type() == representation.INTEGER_REPR {
-		return vm.executeIntegerComparison(left, op, right)
+	if representation.IsNumber(left) && representation.IsNumber(right) {
+		return vm.executeNumberComparison(left, op, right)
 	}
 	switch op {
 	case code.OpEqual:
-		return vm.push(nativeBoolToBooleanrepresentation(right == left))
+		return vm.push(nativeBoolToBooleanrepresentation(representation.Equal(left, right)))
 	case code.OpNotEqual:
-		return vm.push(nativeBoolToBooleanrepresentation(right != left))
+		return vm.push(nativeBoolToBooleanrepresentation(!representation.Equal(left, right)))
+	case code.OpGreaterThan:
+		if !representation.Ordered(left) || !representation.Ordered(right) {
+			return fmt.Errorf("unknown operator: %d (%s %s)", op, left.Type(), right.Type())
+		}
+		order, err := representation.Compare(left, right)
+		if err != nil {
+			return fmt.Errorf("%s", err.Message)
+		}
+		return vm.push(nativeBoolToBooleanrepresentation(order > 0))
 	default:
 		return fmt.Errorf("unknown operator: %d (%s %s)",
 			op, left.Type(), right.Type())
@@ -334,17 +402,15 @@ func (vm *VM) executeComparison(op code.Opcode) error {
 
 }
 
-func (vm *VM) executeIntegerComparison(left representation.Representation, operator code.Opcode, right representation.Representation) error {
-	leftValue := left.(*representation.Integer).Value
-	rightValue := right.(*representation.Integer).Value
-
+func (vm *VM) executeNumberComparison(left representation.Representation, operator code.Opcode, right representation.Representation) error {
 	switch operator {
 	case code.OpEqual:
-		return vm.push(nativeBoolToBooleanrepresentation(rightValue == leftValue))
+		return vm.push(nativeBoolToBooleanrepresentation(representation.Equal(left, right)))
 	case code.OpNotEqual:
-		return vm.push(nativeBoolToBooleanrepresentation(rightValue != leftValue))
+		return vm.push(nativeBoolToBooleanrepresentation(!representation.Equal(left, right)))
 	case code.OpGreaterThan:
-		return vm.push(nativeBoolToBooleanrepresentation(leftValue > rightValue))
+		order, _ := representation.Compare(left, right)
+		return vm.push(nativeBoolToBooleanrepresentation(order > 0))
 	default:
 		return fmt.Errorf("unknown operator: %d", operator)
 	}
@@ -368,12 +434,15 @@ func (vm *VM) executeBangOperator() error {
 func (vm *VM) executeMinusOperator() error {
 	operand := vm.pop()
 
-	if operand.Type() != representation.INTEGER_REPR {
+	if !representation.IsNumber(operand) {
 		return fmt.Errorf("unsupported type for negation: %s", operand.Type())
 	}
 
-	value := operand.(*representation.Integer).Value
-	return vm.push(&representation.Integer{Value: -value})
+	result, err := representation.Negate(operand)
+	if err != nil {
+		return err
+	}
+	return vm.push(result)
 }
 
 func (vm *VM) executeIndexExpression(left representation.Representation, index representation.Representation) error {
@@ -389,26 +458,23 @@ func (vm *VM) executeIndexExpression(left representation.Representation, index r
 }
 
 func (vm *VM) executeArrayIndex(array representation.Representation, index representation.Representation) error {
-	arrayrepresentation := array.(*representation.Array)
-	i := index.(*representation.Integer).Value
-	max := int64(len(arrayrepresentation.Elements) - 1)
-
-	if i < 0 || i > max {
-		return vm.push(Null)
+	element, err := vm.host.Index(array.(*representation.Array), index.(*representation.Integer).Value)
+	if err != nil {
+		return fmt.Errorf("%s", err.Message)
 	}
 
-	return vm.push(arrayrepresentation.Elements[i])
+	return vm.push(element)
 }
 
 func (vm *VM) executeHashIndex(hash representation.Representation, index representation.Representation) error {
 	hashrepresentation := hash.(*representation.Hash)
 
-	key, ok := index.(representation.Hashable)
+	key, ok := representation.HashKeyOf(index)
 	if !ok {
 		return fmt.Errorf("unusable as hash key: %s", index.Type())
 	}
 
-	pair, ok := hashrepresentation.Pairs[key.HashKey()]
+	pair, ok := hashrepresentation.Pairs[key]
 	if !ok {
 		return vm.push(Null)
 	}
@@ -427,30 +493,39 @@ func (vm *VM) buildArray(startIndex int, endIndex int) representation.Representa
 }
 
 func (vm *VM) buildHash(startIndex int, endIndex int) (representation.Representation, error) {
-	hashedPairs := make(map[representation.HashKey]representation.HashPair)
+	hash := representation.NewHash()
 
 	for i := startIndex; i < endIndex; i += 2 {
 		key := vm.stack[i]
 		value := vm.stack[i+1]
 
 		pair := representation.HashPair{Key: key, Value: value}
-		hashKey, ok := key.(representation.Hashable)
+		hashKey, ok := representation.HashKeyOf(key)
 		if !ok {
 			return nil, fmt.Errorf("unusable as hash key: %s", key.Type())
 		}
 
-		hashedPairs[hashKey.HashKey()] = pair
+		hash.Set(hashKey, pair)
 	}
 
-	return &representation.Hash{Pairs: hashedPairs}, nil
+	return hash, nil
 }
 
 func (vm *VM) callBuiltin(builtin *representation.Builtin, argumentNumber int) error {
 	args := vm.stack[vm.stackPointer-argumentNumber : vm.stackPointer]
 
-	results := builtin.Fn(args...)
+	results := builtin.Fn(vm.host, args...)
 	vm.stackPointer = vm.stackPointer - argumentNumber - 1
 
+	// An error returned by a builtin stops the program, as a runtime error
+	// raised by the VM itself does.
+	if err, ok := results.(*representation.Error); ok {
+		return errors.New(err.Message)
+	}
+	if err, ok := results.(error); ok {
+		return err
+	}
+
 	if results != nil {
 		vm.push(results)
 	} else {
@@ -467,6 +542,9 @@ func (vm *VM) callClosure(closure *representation.Closure, argumentNumbers int)
 	}
 
 	frame := NewFrame(closure, vm.stackPointer-argumentNumbers)
+	if frame.basePointer+closure.Fn.NumLocals >= StackSize {
+		return fmt.Errorf("stack overflow")
+	}
 	vm.pushFrame(frame)
 
 	vm.stackPointer = frame.basePointer + closure.Fn.NumLocals
@@ -474,6 +552,47 @@ func (vm *VM) callClosure(closure *representation.Closure, argumentNumbers int)
 	return nil
 }
 
+// call applies fn to args for a builtin, running the VM until fn returns.
+// Errors are returned as values that stop the builtin's caller.
+func (vm *VM) call(fn representation.Representation, args ...representation.Representation) representation.Representation {
+	if builtin, ok := fn.(*representation.Builtin); ok {
+		if result := builtin.Fn(vm.host, args...); result != nil {
+			return result
+		}
+		return Null
+	}
+
+	closure, ok := fn.(*representation.Closure)
+	if !ok {
+		return &representation.Halt{Err: fmt.Errorf("calling non-function and non-built-in")}
+	}
+
+	// A call that fails leaves its frames and values behind, so they are
+	// dropped to keep the VM usable for the next call.
+	depth, stackPointer := vm.framesIndex, vm.stackPointer
+	fail := func(err error) representation.Representation {
+		vm.framesIndex, vm.stackPointer = depth, stackPointer
+		if result, ok := err.(representation.Representation); ok {
+			return result
+		}
+		return &representation.Halt{Err: err}
+	}
+
+	for _, value := range append([]representation.Representation{closure}, args...) {
+		if err := vm.push(value); err != nil {
+			return fail(err)
+		}
+	}
+	if err := vm.callClosure(closure, len(args)); err != nil {
+		return fail(err)
+	}
+
+	if err := vm.run(depth); err != nil {
+		return fail(err)
+	}
+	return vm.pop()
+}
+
 func (vm *VM) executeCall(argumentNumber int) error {
 	calle := vm.stack[vm.stackPointer-1-argumentNumber]
 	switch calle := calle.(type) {
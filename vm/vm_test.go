@@ -1,8 +1,17 @@
 package vm
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/mislavperi/adl-lang/ast"
 	"github.com/mislavperi/adl-lang/compiler"
@@ -35,6 +44,51 @@ func TestIntegerArithmetic(t *testing.T) {
 	runVmTests(t, tests)
 }
 
+func TestFloatArithmetic(t *testing.T) {
+	tests := []vmTestCase{
+		{"1.5", 1.5},
+		{"1e3", 1000.0},
+		{"2.5e-1 * 4", 1.0},
+		{"1.5 + 1", 2.5},
+		{"1 / 2.0", 0.5},
+		{"7 / 2", 3},
+		{"-2.5", -2.5},
+		{"1 < 1.5", true},
+		{"2.0 == 2", true},
+		{"2.0 != 2", false},
+		{"0.1 + 0.2 > 0.3", true},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestArithmeticErrors(t *testing.T) {
+	tests := []vmTestCase{
+		{"MAX_INT + 1", "integer overflow: 9223372036854775807 + 1"},
+		{"MIN_INT - 1", "integer overflow: -9223372036854775808 - 1"},
+		{"MAX_INT * 2", "integer overflow: 9223372036854775807 * 2"},
+		{"MIN_INT / -1", "integer overflow: -9223372036854775808 / -1"},
+		{"-MIN_INT", "integer overflow: -(-9223372036854775808)"},
+		{"1 / 0", "division by zero"},
+		{"1.5 / 0", "division by zero"},
+	}
+
+	for _, tt := range tests {
+		comp := compiler.New()
+		if err := comp.Compile(parse(tt.input)); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		err := New(comp.Bytecode()).Run()
+		if err == nil {
+			t.Fatalf("%s: expected VM error but resulted in none.", tt.input)
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("%s: wrong VM error: want=%q, got=%q", tt.input, tt.expected, err)
+		}
+	}
+}
+
 func TestBooleanExpressions(t *testing.T) {
 	tests := []vmTestCase{
 		{"true", true},
@@ -68,6 +122,90 @@ func TestBooleanExpressions(t *testing.T) {
 	runVmTests(t, tests)
 }
 
+func TestDeepEquality(t *testing.T) {
+	tests := []vmTestCase{
+		{`[1, 2] == [1, 2]`, true},
+		{`[1, 2] != [1, 2]`, false},
+		{`[1, [2, 3]] == [1, [2, 3]]`, true},
+		{`[1, 2] == [2, 1]`, false},
+		{`[1, 2] == [1, 2, 3]`, false},
+		{`[1] == [1.0]`, true},
+		{`{"a": [1]} == {"a": [1]}`, true},
+		{`{"a": 1, "b": 2} == {"b": 2, "a": 1}`, true},
+		{`{"a": 1} != {"a": 2}`, true},
+		{`"ab" == "a" + "b"`, true},
+		{`"a" != "b"`, true},
+		{`[1] == 1`, false},
+		{`"1" != 1`, true},
+		{`let f = fn() { 1 }; f == f`, true},
+		{`fn() { 1 } == fn() { 1 }`, false},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestOrdering(t *testing.T) {
+	tests := []vmTestCase{
+		{`"a" < "b"`, true},
+		{`"b" > "a"`, true},
+		{`"ab" < "abc"`, true},
+		{`"B" < "a"`, true},
+		{`"a" > "a"`, false},
+		{`"é" > "z"`, true},
+		{`[1, 2] < [1, 3]`, true},
+		{`[1, 2] < [1, 2, 0]`, true},
+		{`[2] > [1, 9]`, true},
+		{`[] < [0]`, true},
+		{`[1, 2] > [1, 2]`, false},
+		{`[1, "b"] > [1, "a"]`, true},
+		{`[[1, 2], 3] < [[1, 3], 0]`, true},
+		{`[1] < [1.5]`, true},
+		{`sort(["pear", "apple", "fig"])`, []string{"apple", "fig", "pear"}},
+		{`sort([[2, 1], [1, 2], [1]]) == [[1], [1, 2], [2, 1]]`, true},
+		{`sort(["b", "a"], fn(a, b) { a > b })`, []string{"b", "a"}},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestOrderingErrors(t *testing.T) {
+	tests := []vmTestCase{
+		{`[1] < ["a"]`, "cannot compare STRING with INTEGER"},
+		{`"a" > 1`, "cannot compare STRING with INTEGER"},
+		{`true > false`, "unknown operator: 10 (BOOLEAN BOOLEAN)"},
+	}
+
+	for _, tt := range tests {
+		comp := compiler.New()
+		if err := comp.Compile(parse(tt.input)); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		err := New(comp.Bytecode()).Run()
+		if err == nil {
+			t.Fatalf("%s: expected VM error but resulted in none.", tt.input)
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("%s: wrong VM error: want=%q, got=%q", tt.input, tt.expected, err)
+		}
+	}
+}
+
+func TestArrayHashKeys(t *testing.T) {
+	tests := []vmTestCase{
+		{`{[1, 2]: "a"}[[1, 2]]`, "a"},
+		{`let h = {[1, "x"]: 1, [1, "y"]: 2}; h[[1, "y"]]`, 2},
+		{`{[[1], 2]: 3}[[[1], 2]]`, 3},
+		{`{[1, 2]: "a"}[[2, 1]]`, Null},
+		{`{[]: 0}[[]]`, 0},
+		{`keys({[2]: 1, [1]: 2})[0]`, []int{2}},
+		{`len({[1]: 1, [1]: 2})`, 1},
+		{`has_key({[1]: 1}, [fn() { 1 }])`, &representation.Error{Message: "unusable as hash key: ARRAY"}},
+	}
+
+	runVmTests(t, tests)
+}
+
 func TestConditionals(t *testing.T) {
 	tests := []vmTestCase{
 		{"if (true) { 10 }", 10},
@@ -146,7 +284,9 @@ func TestIndexExpressions(t *testing.T) {
 		{"[[1, 1, 1]][0][0]", 1},
 		{"[][0]", Null},
 		{"[1, 2, 3][99]", Null},
-		{"[1][-1]", Null},
+		{"[1][-1]", 1},
+		{"[1, 2, 3][-2]", 2},
+		{"[1][-2]", Null},
 		{"{1: 1, 2: 2}[1]", 1},
 		{"{1: 1, 2: 2}[2]", 2},
 		{"{1: 1}[0]", Null},
@@ -156,6 +296,65 @@ func TestIndexExpressions(t *testing.T) {
 	runVmTests(t, tests)
 }
 
+func TestSliceExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{"[1, 2, 3, 4][1:3]", []int{2, 3}},
+		{"[1, 2, 3][:2]", []int{1, 2}},
+		{"[1, 2, 3][1:]", []int{2, 3}},
+		{"[1, 2, 3][:]", []int{1, 2, 3}},
+		{"[1, 2, 3][-2:]", []int{2, 3}},
+		{"[1, 2, 3][:-1]", []int{1, 2}},
+		{"[1, 2, 3][1:99]", []int{2, 3}},
+		{"[1, 2, 3][-99:1]", []int{1}},
+		{"[1, 2, 3][2:1]", []int{}},
+		{"let i = 1; [1, 2, 3][i:i + 1]", []int{2}},
+		{`{"a": [1, 2, 3]}["a"][1:][0]`, 2},
+		{`"héllo"[1:3]`, "él"},
+		{`"hello"[-3:]`, "llo"},
+		{`"abc"[5:]`, ""},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestStrictIndexing(t *testing.T) {
+	host := &representation.Host{Strict: true}
+
+	runHostTests(t, host, []vmTestCase{
+		{"[1, 2, 3][-1]", 3},
+		{"[1, 2, 3][1:]", []int{2, 3}},
+		{"[1, 2, 3][-3:3]", []int{1, 2, 3}},
+		{`"abc"[3:]`, ""},
+		{`{}["missing"]`, Null},
+	})
+
+	failures := []vmTestCase{
+		{"[1, 2, 3][3]", "index out of range: 3 with length 3"},
+		{"[1, 2, 3][-4]", "index out of range: -4 with length 3"},
+		{"[1, 2, 3][1:4]", "slice bounds out of range: [1:4] with length 3"},
+		{`"abc"[2:1]`, "slice bounds out of range: [2:1] with length 3"},
+		{"[1][true:]", "slice index must be an integer, got BOOLEAN"},
+		{"{}[1:]", "slice operator not supported: HASH"},
+	}
+
+	for _, tt := range failures {
+		comp := compiler.New()
+		if err := comp.Compile(parse(tt.input)); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		vm := New(comp.Bytecode())
+		vm.SetHost(&representation.Host{Strict: true})
+		err := vm.Run()
+		if err == nil {
+			t.Fatalf("%s: expected VM error but resulted in none.", tt.input)
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("%s: wrong VM error: want=%q, got=%q", tt.input, tt.expected, err)
+		}
+	}
+}
+
 func TestCallingFunctionsWithoutArguments(t *testing.T) {
 	tests := []vmTestCase{
 		{
@@ -420,6 +619,8 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`len("")`, 0},
 		{`len("four")`, 4},
 		{`len("hello world")`, 11},
+		{`len("héllo")`, 5},
+		{`substring("héllo", 1, len("héllo"))`, "éllo"},
 		{
 			`len(1)`,
 			&representation.Error{
@@ -461,6 +662,589 @@ func TestBuiltinFunctions(t *testing.T) {
 	runVmTests(t, tests)
 }
 
+func TestStringBuiltins(t *testing.T) {
+	tests := []vmTestCase{
+		{`split("a,b,,c", ",")`, []string{"a", "b", "", "c"}},
+		{`split("  one two   three ")`, []string{"one", "two", "three"}},
+		{`split("héllo", "")`, []string{"h", "é", "l", "l", "o"}},
+		{`join(["a", "b", "c"], "-")`, "a-b-c"},
+		{`join([])`, ""},
+		{`join(["a", 1])`, &representation.Error{Message: "`join` needs an array of strings, got INTEGER at index 1"}},
+		{`trim("  hi  ")`, "hi"},
+		{`trim("--hi-", "-")`, "hi"},
+		{`upper("héllo")`, "HÉLLO"},
+		{`lower("ÀB")`, "àb"},
+		{`upper(1)`, &representation.Error{Message: "argument to `upper` must be a string, got INTEGER"}},
+		{`contains("hello", "ell")`, true},
+		{`contains("hello", "xyz")`, false},
+		{`contains("hello", "ell") == true`, true},
+		{`starts_with("hello", "he")`, true},
+		{`ends_with("hello", "he")`, false},
+		{`replace("a-b-c", "-", "+")`, "a+b+c"},
+		{`replace("a-b-c", "-", "+", 1)`, "a+b-c"},
+		{`index_of("héllo", "l")`, 2},
+		{`index_of("hello", "z")`, -1},
+		{`substring("héllo", 1, 3)`, "él"},
+		{`substring("héllo", 2)`, "llo"},
+		{`substring("abc", 2, 5)`, &representation.Error{Message: "substring bounds out of range: [2:5] with length 3"}},
+		{`repeat("ab", 3)`, "ababab"},
+		{`repeat("ab", -1)`, &representation.Error{Message: "negative count to `repeat`: -1"}},
+		{`repeat("ab", 9223372036854775807)`, &representation.Error{Message: "`repeat`: result would be longer than 1073741824 bytes"}},
+		{`repeat("", 9223372036854775807)`, ""},
+		{`chars("añb")`, []string{"a", "ñ", "b"}},
+		{`contains("a")`, &representation.Error{Message: "wrong number of arguments, got=1, want=2"}},
+		{`replace("a", "b")`, &representation.Error{Message: "wrong number of arguments, got=2, want=3 or 4"}},
+		{`index_of("a", 1)`, &representation.Error{Message: "argument 2 to `index_of` must be a string, got INTEGER"}},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestArrayBuiltins(t *testing.T) {
+	tests := []vmTestCase{
+		{`map([1, 2, 3], fn(x) { x * 2 })`, []int{2, 4, 6}},
+		{`let k = 10; map([1, 2], fn(x) { x + k })`, []int{11, 12}},
+		{`map(["a", "b"], upper)`, []string{"A", "B"}},
+		{`map([], fn(x) { x })`, []int{}},
+		{`map([1], 1)`, &representation.Error{Message: "argument 2 to `map` must be a function, got INTEGER"}},
+		{`filter(range(10), fn(x) { x / 2 * 2 == x })`, []int{0, 2, 4, 6, 8}},
+		{`reduce([1, 2, 3, 4], fn(acc, x) { acc + x })`, 10},
+		{`reduce([], fn(acc, x) { acc + x }, 5)`, 5},
+		{`reduce([], fn(acc, x) { acc + x })`, &representation.Error{Message: "`reduce` of an empty array needs an initial value"}},
+		{`sort([3, 1, 2])`, []int{1, 2, 3}},
+		{`sort(["b", "c", "a"])`, []string{"a", "b", "c"}},
+		{`sort([3, 1, 2], fn(a, b) { a > b })`, []int{3, 2, 1}},
+		{`sort([[2, "b"], [1, "a"]], fn(a, b) { a[0] < b[0] })[0][1]`, "a"},
+		{`sort([1, "a"])`, &representation.Error{Message: "cannot compare STRING with INTEGER"}},
+		{`reverse([1, 2, 3])`, []int{3, 2, 1}},
+		{`contains([1, [2, 3]], [2, 3])`, true},
+		{`contains([1, 2], 3)`, false},
+		{`index_of(["a", "b"], "b")`, 1},
+		{`index_of([1, 2], 3)`, -1},
+		{`zip([1, 2, 3], ["a", "b"])[1][1]`, "b"},
+		{`len(zip([1, 2, 3], ["a", "b"]))`, 2},
+		{`flatten([1, [2, [3]], 4])[2][0]`, 3},
+		{`flatten([1, [2, [3]], 4], 2)`, []int{1, 2, 3, 4}},
+		{`range(4)`, []int{0, 1, 2, 3}},
+		{`range(2, 5)`, []int{2, 3, 4}},
+		{`range(5, 0, -2)`, []int{5, 3, 1}},
+		{`range(1, 2, 0)`, &representation.Error{Message: "`range` step must not be zero"}},
+		{`range(9223372036854775806, 9223372036854775807, 5)`, []int{9223372036854775806}},
+		{`len(range(MAX_INT - 10, MAX_INT, 3))`, 4},
+		{`len(range(MIN_INT + 10, MIN_INT, -3))`, 4},
+		{`slice([1, 2, 3, 4], 1, 3)`, []int{2, 3}},
+		{`slice([1, 2, 3, 4], 2)`, []int{3, 4}},
+		{`slice([1, 2], 1, 3)`, &representation.Error{Message: "slice bounds out of range: [1:3] with length 2"}},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestArrayBuiltinsPropagateFailures(t *testing.T) {
+	comp := compiler.New()
+	if err := comp.Compile(parse(`map([1, 2, 3], fn(x) { assert(x < 2, "too big") }); 1`)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	err := New(comp.Bytecode()).Run()
+	failure, ok := err.(*representation.Failure)
+	if !ok {
+		t.Fatalf("expected failure, got %T (%v)", err, err)
+	}
+	if failure.Message != "too big" {
+		t.Errorf("wrong failure message. want=%q, got=%q", "too big", failure.Message)
+	}
+}
+
+func TestHashBuiltins(t *testing.T) {
+	tests := []vmTestCase{
+		{`keys({"b": 1, "a": 2, "c": 3})`, []string{"b", "a", "c"}},
+		{`values({"b": 1, "a": 2, "c": 3})`, []int{1, 2, 3}},
+		{`keys({})`, []string{}},
+		{`entries({"x": 1, "y": 2})[1][0]`, "y"},
+		{`has_key({"x": 1}, "x")`, true},
+		{`has_key({"x": 1}, "y")`, false},
+		{`has_key({"x": 1}, [1])`, false},
+		{`has_key({"x": 1}, [len])`, &representation.Error{Message: "unusable as hash key: ARRAY"}},
+		{`keys(delete({"a": 1, "b": 2, "c": 3}, "b"))`, []string{"a", "c"}},
+		{`let h = {"a": 1}; delete(h, "a"); len(h)`, 1},
+		{`keys(merge({"a": 1, "b": 2}, {"c": 3, "a": 4}))`, []string{"a", "b", "c"}},
+		{`merge({"a": 1, "b": 2}, {"c": 3, "a": 4})["a"]`, 4},
+		{`merge({}, 1)`, &representation.Error{Message: "argument 2 to `merge` must be a hash, got INTEGER"}},
+		{`len({1: 2, 3: 4})`, 2},
+		{`keys([])`, &representation.Error{Message: "argument to `keys` must be a hash, got ARRAY"}},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestHashInsertionOrder(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"z": 1, "a": 2, 10: 3, true: 4}`, `{z: 1, a: 2, 10: 3, true: 4}`},
+		{`{"a": 1, "b": 2, "a": 3}`, `{a: 3, b: 2}`},
+		{`merge({3: 3}, {1: 1}, {2: 2})`, `{3: 3, 1: 1, 2: 2}`},
+	}
+
+	for _, tt := range tests {
+		comp := compiler.New()
+		if err := comp.Compile(parse(tt.input)); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+		vm := New(comp.Bytecode())
+		if err := vm.Run(); err != nil {
+			t.Fatalf("vm error: %s", err)
+		}
+
+		if got := vm.LastPoppedStackElem().Inspect(); got != tt.expected {
+			t.Errorf("wrong hash. want=%s, got=%s", tt.expected, got)
+		}
+	}
+}
+
+func TestJSONBuiltins(t *testing.T) {
+	tests := []vmTestCase{
+		{`json_stringify({"b": [1, true, "x"], "a": if (false) { 1 }})`, `{"b":[1,true,"x"],"a":null}`},
+		{`json_stringify([1, [2]], 2)`, "[\n  1,\n  [\n    2\n  ]\n]"},
+		{`json_stringify({"k": 1}, "--")`, "{\n--\"k\": 1\n}"},
+		{`json_stringify({1: "<&>"})`, `{"1":"<&>"}`},
+		{`json_stringify(fn() { 1 })`, &representation.Error{Message: "cannot convert CLOSURE to JSON"}},
+		{`json_stringify([len])`, &representation.Error{Message: "cannot convert BUILTIN to JSON"}},
+		{`json_parse(json_stringify({"b": [1, 2], "a": true}))["b"]`, []int{1, 2}},
+		{`keys(json_parse(json_stringify({"z": 1, "a": 2})))`, []string{"z", "a"}},
+		{`json_parse(" [1, -2, 30] ")`, []int{1, -2, 30}},
+		{`json_parse("true")`, true},
+		{`json_parse("null")`, Null},
+		{`json_parse("[1, 2")`, &representation.Error{Message: "invalid JSON at offset 5: unexpected end of input"}},
+		{`json_parse("[1 2]")`, &representation.Error{Message: "invalid JSON at offset 3: invalid character '2' after array element"}},
+		{`json_parse("1 2")`, &representation.Error{Message: "invalid JSON at offset 2: unexpected data after the value"}},
+		{`json_parse("[1.5, 2e2]")[1]`, 200.0},
+		{`json_parse("[123456789012345678901]")`, &representation.Error{Message: "invalid JSON at offset 1: number 123456789012345678901 does not fit in an integer"}},
+		{`json_stringify([1.5, 2.0])`, "[1.5,2.0]"},
+		{`json_stringify(pow(10.0, 400))`, &representation.Error{Message: "cannot convert +Inf to JSON"}},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestMathBuiltins(t *testing.T) {
+	tests := []vmTestCase{
+		{`PI`, math.Pi},
+		{`E`, math.E},
+		{`abs(-3)`, 3},
+		{`abs(-2.5)`, 2.5},
+		{`abs(MIN_INT)`, &representation.Error{Message: "integer overflow: -(-9223372036854775808)"}},
+		{`min(3, 1, 2)`, 1},
+		{`max([1, 5.5, 2])`, 5.5},
+		{`min([])`, &representation.Error{Message: "`min` of an empty array"}},
+		{`max(1, "a")`, &representation.Error{Message: "`max` needs numbers, got STRING"}},
+		{`pow(2, 10)`, 1024},
+		{`pow(2, -1)`, 0.5},
+		{`pow(2.0, 3)`, 8.0},
+		{`pow(-2, 63) == MIN_INT`, true},
+		{`pow(2, 63)`, &representation.Error{Message: "integer overflow: pow(2, 63)"}},
+		{`sqrt(16)`, 4.0},
+		{`sqrt(-1)`, &representation.Error{Message: "argument to `sqrt` must not be negative, got -1"}},
+		{`sqrt("a")`, &representation.Error{Message: "argument to `sqrt` must be a number, got STRING"}},
+		{`floor(2.7)`, 2},
+		{`ceil(2.1)`, 3},
+		{`round(2.5)`, 3},
+		{`round(-2.5)`, -3},
+		{`floor(5)`, 5},
+		{`floor(1e300)`, &representation.Error{Message: "`floor`: 1e+300 does not fit in an integer"}},
+		{`clamp(15, 0, 10)`, 10},
+		{`clamp(-1.5, 0, 10)`, 0},
+		{`clamp(2.5, 0, 10)`, 2.5},
+		{`clamp(5, 10, 0)`, &representation.Error{Message: "`clamp`: lower bound 10 is above upper bound 0"}},
+		{`sin(0)`, 0.0},
+		{`cos(PI)`, -1.0},
+		{`atan(1) * 4`, math.Pi},
+		{`atan(-1, -1)`, -3 * math.Pi / 4},
+		{`exp(0)`, 1.0},
+		{`log(E)`, 1.0},
+		{`log(8, 2)`, 3.0},
+		{`log(0)`, &representation.Error{Message: "argument to `log` must be positive, got 0"}},
+		{`format("%.2f|%e|%g", PI, 1500, 0.5)`, "3.14|1.500000e+03|0.5"},
+		{`format("%f", "x")`, &representation.Error{Message: "format: %f needs a number, got STRING"}},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestRandomBuiltins(t *testing.T) {
+	host := &representation.Host{Rand: rand.New(rand.NewSource(1))}
+
+	runHostTests(t, host, []vmTestCase{
+		{`let xs = map(range(0, 200), fn(i) { random_int(1, 3) }); [min(xs), max(xs)]`, []int{1, 3}},
+		{`max(map(range(0, 200), fn(i) { random() })) < 1`, true},
+		{`min(map(range(0, 200), fn(i) { random() })) < 0`, false},
+		{`random_int(4, 4)`, 4},
+		{`let n = random_int(MIN_INT, MAX_INT); n == n`, true},
+		{`sort(shuffle([3, 1, 2]))`, []int{1, 2, 3}},
+		{`let a = [1, 2, 3]; shuffle(a); a`, []int{1, 2, 3}},
+		{`choice([7])`, 7},
+		{`choice([])`, &representation.Error{Message: "`choice` of an empty array"}},
+		{`random_int(5, 1)`, &representation.Error{Message: "`random_int`: lower bound 5 is above upper bound 1"}},
+		{`random_int(1.5, 2)`, &representation.Error{Message: "argument 1 to `random_int` must be an integer, got FLOAT"}},
+	})
+
+	draw := func(seed int64) string {
+		comp := compiler.New()
+		if err := comp.Compile(parse(`[shuffle(range(0, 20)), random_int(0, 1000), choice(range(0, 1000))]`)); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+		vm := New(comp.Bytecode())
+		vm.SetHost(&representation.Host{Rand: rand.New(rand.NewSource(seed))})
+		if err := vm.Run(); err != nil {
+			t.Fatalf("vm error: %s", err)
+		}
+		return vm.LastPoppedStackElem().Inspect()
+	}
+
+	if a, b := draw(7), draw(7); a != b {
+		t.Errorf("same seed gave different values: %s and %s", a, b)
+	}
+	if a, b := draw(7), draw(8); a == b {
+		t.Errorf("different seeds gave the same values: %s", a)
+	}
+}
+
+func TestRegexBuiltins(t *testing.T) {
+	tests := []vmTestCase{
+		{`match("a1b22", "[a-z]([0-9]+)")`, []string{"a1", "1"}},
+		{`match("abc", "[0-9]")`, Null},
+		{`match("ab", "a(x)?b")[1]`, Null},
+		{`match("a1", "\d")`, []string{"1"}},
+		{`captures("2024-03", "(?P<year>[0-9]+)-(?P<month>[0-9]+)")["month"]`, "03"},
+		{`keys(captures("2024-03", "(?P<year>[0-9]+)-(?P<month>[0-9]+)"))`, []string{"year", "month"}},
+		{`captures("x", "(?P<n>[0-9])")`, Null},
+		{`map(find_all("a1b22c333", "[0-9]+"), first)`, []string{"1", "22", "333"}},
+		{`len(find_all("a1b22c333", "[0-9]+", 2))`, 2},
+		{`find_all("k=v, x=y", "(\w)=(\w)")[1]`, []string{"x=y", "x", "y"}},
+		{`replace_regex("a1b22", "[0-9]+", "#")`, "a#b#"},
+		{`replace_regex("john smith", "(\w+) (\w+)", "$2, $1")`, "smith, john"},
+		{`replace_regex("a1b22", "[0-9]+", fn(m) { "<" + m[0] + ">" })`, "a<1>b<22>"},
+		{`replace_regex("a1", "[0-9]", fn(m) { 1 })`, &representation.Error{Message: "replace_regex: the function must return a string, got INTEGER"}},
+		{`replace_regex("a1", "[0-9]", 1)`, &representation.Error{Message: "argument 3 to `replace_regex` must be a string or a function, got INTEGER"}},
+		{`split_regex("a, b;c", "[,;] *")`, []string{"a", "b", "c"}},
+		{`split_regex("a, b;c", "[,;] *", 2)`, []string{"a", "b;c"}},
+		{`match("x", "(")`, &representation.Error{Message: `match: invalid pattern "(": missing closing )`}},
+		{`find_all("x", "a**")`, &representation.Error{Message: `find_all: invalid pattern "a**": invalid nested repetition operator`}},
+		{`split_regex("x", 1)`, &representation.Error{Message: "argument 2 to `split_regex` must be a string, got INTEGER"}},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestConversionBuiltins(t *testing.T) {
+	tests := []vmTestCase{
+		{`map([1, 1.5, "a", true, if (false) { 1 }, [], {}], type)`, []string{"integer", "float", "string", "boolean", "null", "array", "hash"}},
+		{`map([fn() { 1 }, len], type)`, []string{"function", "function"}},
+		{`int("42")`, 42},
+		{`int(" -7 ")`, -7},
+		{`int("ff", 16)`, 255},
+		{`int(2.9)`, 2},
+		{`int(-2.9)`, -2},
+		{`int(true)`, 1},
+		{`int("4.5")`, &representation.Error{Message: "`int`: cannot parse \"4.5\" as an integer"}},
+		{`int("99999999999999999999")`, &representation.Error{Message: "`int`: \"99999999999999999999\" does not fit in an integer"}},
+		{`int("1", 99)`, &representation.Error{Message: "`int`: base 99 is not between 2 and 36"}},
+		{`int(1, 2)`, &representation.Error{Message: "`int`: a base needs a string to parse, got INTEGER"}},
+		{`int([])`, &representation.Error{Message: "`int`: cannot convert ARRAY to an integer"}},
+		{`float("2.5")`, 2.5},
+		{`float(3)`, 3.0},
+		{`float("1e3")`, 1000.0},
+		{`float("x")`, &representation.Error{Message: "`float`: cannot parse \"x\" as a float"}},
+		{`str(12) + str([1, "a"]) + str("s")`, "12[1, a]s"},
+		{`str(if (false) { 1 })`, "null"},
+		{`map([0, false, if (false) { 1 }, [], {}], bool)`, []bool{true, false, false, true, true}},
+		{`[bool("true"), bool(" false ")]`, []bool{true, false}},
+		{`bool("")`, &representation.Error{Message: "`bool`: cannot parse \"\" as a boolean"}},
+		{`bool("yes")`, &representation.Error{Message: "`bool`: cannot parse \"yes\" as a boolean"}},
+		{`map([1, 1.5, "1"], is_number)`, []bool{true, true, false}},
+		{`[is_int(1), is_int(1.0), is_float(1.0), is_string("a"), is_bool(false)]`, []bool{true, false, true, true, true}},
+		{`[is_array([]), is_hash({}), is_null(if (false) { 1 }), is_function(len), is_function(fn() { 1 })]`, []bool{true, true, true, true, true}},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestTimeBuiltins(t *testing.T) {
+	frozen := time.Date(2024, 2, 29, 12, 30, 0, 0, time.UTC)
+	host := &representation.Host{Clock: func() time.Time { return frozen }}
+
+	runHostTests(t, host, []vmTestCase{
+		{`now()`, int(frozen.UnixMilli())},
+		{`unix()`, int(frozen.Unix())},
+		{`format_time(now(), "2006-01-02 15:04")`, "2024-02-29 12:30"},
+		{`format_time(now() + DAY, "Jan 2")`, "Mar 1"},
+		{`format_time(0, "2006-01-02T15:04:05Z07:00", "UTC")`, "1970-01-01T00:00:00Z"},
+		{`format_time(0, "2006", "Mars/Olympus")`, &representation.Error{Message: `format_time: unknown time zone "Mars/Olympus"`}},
+		{`parse_time("2024-03-01", "2006-01-02") - parse_time("2024-02-29", "2006-01-02") == DAY`, true},
+		{`parse_time("2024-02-29 12:30", "2006-01-02 15:04") == now()`, true},
+		{`parse_time("nope", "2006-01-02")`, &representation.Error{Message: `parse_time: parsing time "nope" as "2006-01-02": cannot parse "nope" as "2006"`}},
+		{`duration("1h30m") == HOUR + 30 * MINUTE`, true},
+		{`duration("250ms")`, 250},
+		{`duration("soon")`, &representation.Error{Message: `duration: time: invalid duration "soon"`}},
+		{`format_duration(90 * MINUTE + 5 * SECOND)`, "1h30m5s"},
+		{`sleep(1)`, Null},
+		{`sleep(-1)`, &representation.Error{Message: "argument to `sleep` must not be negative, got -1"}},
+	})
+}
+
+func TestCancellation(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancelTimeout := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelTimeout()
+
+	// The context goes first so that the engine tests, which replay the
+	// leading string of every case, do not sleep for a minute.
+	tests := []struct {
+		context  context.Context
+		input    string
+		expected error
+	}{
+		{cancelled, `let f = fn() { 1 }; f()`, context.Canceled},
+		{expired, `sleep(60 * SECOND); 1`, context.DeadlineExceeded},
+		{expired, `map([1], fn(x) { sleep(60 * SECOND) })`, context.DeadlineExceeded},
+	}
+
+	for _, tt := range tests {
+		comp := compiler.New()
+		if err := comp.Compile(parse(tt.input)); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		vm := New(comp.Bytecode())
+		vm.SetHost(&representation.Host{Context: tt.context})
+		start := time.Now()
+		err := vm.Run()
+		if !errors.Is(err, tt.expected) {
+			t.Errorf("%s: wrong error. want=%v, got=%v", tt.input, tt.expected, err)
+		}
+		if elapsed := time.Since(start); elapsed > 10*time.Second {
+			t.Errorf("%s: cancellation took %s", tt.input, elapsed)
+		}
+	}
+}
+
+func TestHostBuiltins(t *testing.T) {
+	host := &representation.Host{
+		Args: []string{"one", "two"},
+		LookupEnv: func(name string) (string, bool) {
+			if name == "HOME" {
+				return "/home/adl", true
+			}
+			return "", false
+		},
+	}
+
+	tests := []vmTestCase{
+		{`args()`, []string{"one", "two"}},
+		{`len(args())`, 2},
+		{`env("HOME")`, "/home/adl"},
+		{`env("MISSING")`, Null},
+		{`env(1)`, &representation.Error{Message: "argument to `env` must be a string, got INTEGER"}},
+	}
+
+	runHostTests(t, host, tests)
+}
+
+func TestFileBuiltins(t *testing.T) {
+	dir := t.TempDir()
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret"), []byte("s"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	path := func(name string) string { return strconv.Quote(filepath.Join(dir, name)) }
+	host := &representation.Host{FS: &representation.FilePolicy{Roots: []string{dir}}}
+
+	runHostTests(t, host, []vmTestCase{
+		{`exists(` + path("a.txt") + `)`, false},
+		{`write_file(` + path("a.txt") + `, "one")`, Null},
+		{`append_file(` + path("a.txt") + `, " two")`, Null},
+		{`read_file(` + path("a.txt") + `)`, "one two"},
+		{`exists(` + path("a.txt") + `)`, true},
+		{`list_dir(` + strconv.Quote(dir) + `)`, []string{"a.txt", "link"}},
+		{`remove(` + path("a.txt") + `); exists(` + path("a.txt") + `)`, false},
+		{`read_file(` + path("a.txt") + `)`, &representation.Error{
+			Message: "open " + filepath.Join(dir, "a.txt") + ": no such file or directory"}},
+		{`read_file(` + strconv.Quote(filepath.Join(outside, "secret")) + `)`, &representation.Error{
+			Message: "read_file: access to " + filepath.Join(outside, "secret") + " is outside the allowed directories"}},
+		{`read_file(` + path("link/secret") + `)`, &representation.Error{
+			Message: "read_file: access to " + filepath.Join(dir, "link/secret") + " is outside the allowed directories"}},
+		{`write_file(` + path("../escape") + `, "x")`, &representation.Error{
+			Message: "write_file: access to " + filepath.Join(dir, "../escape") + " is outside the allowed directories"}},
+		{`write_file(` + path("b.txt") + `, 1)`, &representation.Error{
+			Message: "argument 2 to `write_file` must be a string, got INTEGER"}},
+	})
+
+	readOnly := &representation.Host{FS: &representation.FilePolicy{Roots: []string{dir}, ReadOnly: true}}
+	runHostTests(t, readOnly, []vmTestCase{
+		{`write_file(` + path("b.txt") + `, "x")`, &representation.Error{Message: "write_file: file system is read-only"}},
+		{`remove(` + path("link") + `)`, &representation.Error{Message: "remove: file system is read-only"}},
+		{`exists(` + strconv.Quote(dir) + `)`, true},
+	})
+
+	runHostTests(t, representation.NewHost(), []vmTestCase{
+		{`exists(` + path("link") + `)`, &representation.Error{Message: "exists: file access is disabled"}},
+	})
+}
+
+func TestOutputBuiltins(t *testing.T) {
+	var stdout strings.Builder
+	host := &representation.Host{Stdout: &stdout, Stdin: strings.NewReader("first\r\nsecond\nlast")}
+
+	runHostTests(t, host, []vmTestCase{
+		{`out("a", [1, "b"])`, Null},
+		{`print("no", "newline", 1)`, Null},
+		{`printf("%d-%5.1s|%-4v|%x|%q|%t|100%%", 42, "xyz", [1], 255, "q", true)`, Null},
+		{`input("name? ")`, "first"},
+		{`read_line()`, "second"},
+		{`read_line()`, "last"},
+		{`read_line()`, Null},
+		{`format("%3d|%s", 7, {"k": "v"})`, "  7|{k: v}"},
+		{`format("%d", "x")`, &representation.Error{Message: "format: %d needs an integer, got STRING"}},
+		{`format("%d %d", 1)`, &representation.Error{Message: "format: missing argument for %d"}},
+		{`format("%d", 1, 2)`, &representation.Error{Message: "format: 1 arguments left over"}},
+		{`format("%y", 1)`, &representation.Error{Message: "format: unknown verb %y"}},
+		{`format("100%")`, &representation.Error{Message: "format: unterminated verb \"%\""}},
+	})
+
+	want := "a\n[1, b]\nnonewline142-    x|[1] |ff|\"q\"|true|100%name? "
+	if stdout.String() != want {
+		t.Errorf("wrong output. want=%q, got=%q", want, stdout.String())
+	}
+}
+
+func runHostTests(t *testing.T, host *representation.Host, tests []vmTestCase) {
+	t.Helper()
+
+	for _, tt := range tests {
+		comp := compiler.New()
+		if err := comp.Compile(parse(tt.input)); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		vm := New(comp.Bytecode())
+		vm.SetHost(host)
+		if err := vm.Run(); err != nil {
+			testExpectedError(t, tt.expected, err)
+			continue
+		}
+
+		testExpectedRepresentation(t, tt.expected, vm.LastPoppedStackElem())
+	}
+}
+
+func TestExit(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int
+	}{
+		{`exit(); 1`, 0},
+		{`exit(2); 1`, 2},
+		{`let f = fn(x) { if (x > 1) { exit(x) }; 0 }; f(7); 1`, 7},
+	}
+
+	for _, tt := range tests {
+		comp := compiler.New()
+		if err := comp.Compile(parse(tt.input)); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		err := New(comp.Bytecode()).Run()
+		exit, ok := err.(*representation.Exit)
+		if !ok {
+			t.Fatalf("expected exit, got %T (%v)", err, err)
+		}
+		if exit.Code != tt.expected {
+			t.Errorf("wrong exit code. want=%d, got=%d", tt.expected, exit.Code)
+		}
+	}
+}
+
+func TestAssertions(t *testing.T) {
+	tests := []struct {
+		input   string
+		failure string
+	}{
+		{`assert(1 < 2); 1`, ""},
+		{`assert(1 > 2); 1`, "assertion failed"},
+		{`assert(if (false) { 1 }, "no value"); 1`, "no value"},
+		{`assert_eq([1, 2], [1, 2]); 1`, ""},
+		{`assert_eq(1, "1"); 1`, "values are not equal\nwant: 1 (STRING)\n got: 1 (INTEGER)"},
+		{`assert_eq([1, 2, 3], [1, 5, 3]); 1`, "values are not equal\nwant: [1, 5, 3]\n got: [1, 2, 3]\n          ^"},
+		{`test("inline", fn() { assert_eq(1, 1) }); 1`, ""},
+		{`let f = fn(x) { assert(x > 0, "positive") }; test("nested", fn() { f(1); f(0); 2 }); 1`, "positive"},
+	}
+
+	for _, tt := range tests {
+		comp := compiler.New()
+		if err := comp.Compile(parse(tt.input)); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		err := New(comp.Bytecode()).Run()
+		if tt.failure == "" {
+			if err != nil {
+				t.Errorf("vm error for %q: %s", tt.input, err)
+			}
+			continue
+		}
+
+		failure, ok := err.(*representation.Failure)
+		if !ok {
+			t.Errorf("expected failure, got %T (%v)", err, err)
+			continue
+		}
+		if failure.Message != tt.failure {
+			t.Errorf("wrong failure message. want=%q, got=%q", tt.failure, failure.Message)
+		}
+	}
+}
+
+func TestCallFromBuiltin(t *testing.T) {
+	comp := compiler.New()
+	if err := comp.Compile(parse(`let n = 5; let add = fn(a, b) { a + b + n }; add(1, 1)`)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	vm := New(comp.Bytecode())
+	if err := vm.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+
+	add := vm.globals[1]
+	result := vm.host.Call(add, &representation.Integer{Value: 3}, &representation.Integer{Value: 4})
+	testExpectedRepresentation(t, 12, result)
+
+	// A call that fails deep in recursion leaves the VM as it found it.
+	comp = compiler.New()
+	if err := comp.Compile(parse(`let down = fn(n) { if (n == 0) { assert(false) } else { down(n - 1) } }; 1`)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	vm = New(comp.Bytecode())
+	if err := vm.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+	framesIndex, stackPointer := vm.framesIndex, vm.stackPointer
+	if _, ok := vm.host.Call(vm.globals[0], &representation.Integer{Value: 50}).(*representation.Failure); !ok {
+		t.Fatalf("expected the call to fail")
+	}
+	if vm.framesIndex != framesIndex || vm.stackPointer != stackPointer {
+		t.Errorf("failed call left frames %d and stack %d, want %d and %d",
+			vm.framesIndex, vm.stackPointer, framesIndex, stackPointer)
+	}
+}
+
 func TestClosures(t *testing.T) {
 	tests := []vmTestCase{
 		{
@@ -592,6 +1376,50 @@ func TestRecursiveFunctions(t *testing.T) {
 	runVmTests(t, tests)
 }
 
+func TestWideOperands(t *testing.T) {
+	params := make([]string, 300)
+	args := make([]string, 300)
+	for i := range params {
+		// Identifiers cannot contain digits, so the names are spelled in letters.
+		params[i] = "p" + string(rune('a'+i/26)) + string(rune('a'+i%26))
+		args[i] = fmt.Sprintf("%d", i)
+	}
+
+	tests := []vmTestCase{
+		{
+			input: fmt.Sprintf("fn(%s) { %s + %s }(%s)",
+				strings.Join(params, ", "), params[0], params[299], strings.Join(args, ", ")),
+			expected: 299,
+		},
+		{
+			input: fmt.Sprintf("let f = fn(%s) { fn() { %s + %s } }; f(%s)()",
+				strings.Join(params, ", "), params[1], params[298], strings.Join(args, ", ")),
+			expected: 299,
+		},
+		{
+			input: fmt.Sprintf("let f = fn(%s) { fn() { %s } }; f(%s)()",
+				strings.Join(params, ", "), strings.Join(params, " + "), strings.Join(args, ", ")),
+			expected: 299 * 300 / 2,
+		},
+	}
+
+	runVmTests(t, tests)
+}
+
+// testExpectedError checks the error a program stopped with against the
+// *representation.Error a test expected, builtin errors stopping the VM.
+func testExpectedError(t *testing.T, expected interface{}, err error) {
+	t.Helper()
+
+	want, ok := expected.(*representation.Error)
+	if !ok {
+		t.Fatalf("vm error: %s", err)
+	}
+	if err.Error() != want.Message {
+		t.Errorf("wrong error message. expected=%q, got=%q", want.Message, err)
+	}
+}
+
 type vmTestCase struct {
 	input    string
 	expected interface{}
@@ -612,7 +1440,8 @@ func runVmTests(t *testing.T, tests []vmTestCase) {
 		vm := New(comp.Bytecode())
 		err = vm.Run()
 		if err != nil {
-			t.Fatalf("vm error: %s", err)
+			testExpectedError(t, tt.expected, err)
+			continue
 		}
 
 		stackElem := vm.LastPoppedStackElem()
@@ -641,6 +1470,12 @@ func testExpectedRepresentation(
 			t.Errorf("testIntegerRepresentation failed: %s", err)
 		}
 
+	case float64:
+		err := testFloatRepresentation(expected, actual)
+		if err != nil {
+			t.Errorf("testFloatRepresentation failed: %s", err)
+		}
+
 	case bool:
 		err := testBooleanRepresentation(bool(expected), actual)
 		if err != nil {
@@ -703,6 +1538,46 @@ func testExpectedRepresentation(
 			}
 		}
 
+	case []string:
+		array, ok := actual.(*representation.Array)
+		if !ok {
+			t.Errorf("representation not Array: %T (%+v)", actual, actual)
+			return
+		}
+
+		if len(array.Elements) != len(expected) {
+			t.Errorf("wrong num of elements. want=%d, got=%d",
+				len(expected), len(array.Elements))
+			return
+		}
+
+		for i, expectedElem := range expected {
+			err := testStringRepresentation(expectedElem, array.Elements[i])
+			if err != nil {
+				t.Errorf("testStringRepresentation failed: %s", err)
+			}
+		}
+
+	case []bool:
+		array, ok := actual.(*representation.Array)
+		if !ok {
+			t.Errorf("representation not Array: %T (%+v)", actual, actual)
+			return
+		}
+
+		if len(array.Elements) != len(expected) {
+			t.Errorf("wrong num of elements. want=%d, got=%d",
+				len(expected), len(array.Elements))
+			return
+		}
+
+		for i, expectedElem := range expected {
+			err := testBooleanRepresentation(expectedElem, array.Elements[i])
+			if err != nil {
+				t.Errorf("testBooleanRepresentation failed: %s", err)
+			}
+		}
+
 	case *representation.Error:
 		errObj, ok := actual.(*representation.Error)
 		if !ok {
@@ -731,6 +1606,21 @@ func testIntegerRepresentation(expected int64, actual representation.Representat
 	return nil
 }
 
+func testFloatRepresentation(expected float64, actual representation.Representation) error {
+	result, ok := actual.(*representation.Float)
+	if !ok {
+		return fmt.Errorf("representation is not Float. got=%T (%+v)",
+			actual, actual)
+	}
+
+	if math.Abs(result.Value-expected) > 1e-9 {
+		return fmt.Errorf("representation has wrong value. got=%g, want=%g",
+			result.Value, expected)
+	}
+
+	return nil
+}
+
 func testBooleanRepresentation(expected bool, actual representation.Representation) error {
 	result, ok := actual.(*representation.Boolean)
 	if !ok {